@@ -0,0 +1,239 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lasfh/eletrize/control"
+	"github.com/lasfh/eletrize/workspace"
+)
+
+const logsPollInterval = 500 * time.Millisecond
+
+// findInstance returns the socket of the control API of the eletrize
+// running in the current directory or in the closest of its parents.
+func findInstance() (string, error) {
+	if err := workspace.PrepareRuntimeDir(); err != nil {
+		return "", err
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		socket := control.SocketPath(dir)
+		if _, err := os.Stat(socket); err == nil {
+			return socket, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", errors.New("no eletrize instance is running in this directory")
+		}
+
+		dir = parent
+	}
+}
+
+// send sends request to the eletrize running in the current directory.
+func send(request control.Request) (control.Response, error) {
+	socket, err := findInstance()
+	if err != nil {
+		return control.Response{}, err
+	}
+
+	response, err := control.Send(socket, request)
+	if err != nil {
+		return control.Response{}, fmt.Errorf("eletrize is not responding: %w", err)
+	}
+
+	if response.Error != "" {
+		return response, errors.New(response.Error)
+	}
+
+	return response, nil
+}
+
+// schemaNumber returns the number of the schema given by its number or label.
+func schemaNumber(args []string) (int, error) {
+	if len(args) == 0 {
+		return 0, nil
+	}
+
+	if number, err := strconv.Atoi(args[0]); err == nil {
+		return number, nil
+	}
+
+	response, err := send(control.Request{Action: control.ActionStatus, Lines: -1})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, schema := range response.Schemas {
+		if schema.Label == args[0] {
+			return schema.Number, nil
+		}
+	}
+
+	return 0, fmt.Errorf("schema not found: %s", args[0])
+}
+
+func statusCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status [schema]",
+		Short: "Show the state of the running eletrize",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			schema, err := schemaNumber(args)
+			if err != nil {
+				return err
+			}
+
+			response, err := send(control.Request{Action: control.ActionStatus, Schema: schema, Lines: -1})
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+			for _, schema := range response.Schemas {
+				printSchemaStatus(w, schema)
+			}
+
+			return w.Flush()
+		},
+	}
+}
+
+func printSchemaStatus(w *tabwriter.Writer, schema control.SchemaStatus) {
+	name := strconv.Itoa(schema.Number)
+	if schema.Label != "" {
+		name += " " + schema.Label
+	}
+
+	if schema.Error != "" {
+		fmt.Fprintf(w, "SCHEMA %s\t%s\n", name, schema.Error)
+
+		return
+	}
+
+	state := schema.State
+	if schema.Paused {
+		state += ", paused"
+	}
+
+	fmt.Fprintf(w, "SCHEMA %s\t%s\t%d restart(s)\n", name, state, schema.Restarts)
+
+	for _, command := range schema.Commands {
+		details := command.Error
+		if command.PID != 0 {
+			uptime := time.Duration(command.Uptime * float64(time.Second)).Round(time.Second)
+			details = fmt.Sprintf("pid %d, up %s", command.PID, uptime)
+		}
+
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", command.Name, command.Kind, command.State, details)
+	}
+}
+
+// actionCommand returns a subcommand sending action to a schema, or to every
+// schema when none is given.
+func actionCommand(action, short string, perCommand bool) *cobra.Command {
+	var name string
+
+	cmd := &cobra.Command{
+		Use:   action + " [schema]",
+		Short: short,
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			schema, err := schemaNumber(args)
+			if err != nil {
+				return err
+			}
+
+			_, err = send(control.Request{Action: action, Schema: schema, Command: name})
+
+			return err
+		},
+	}
+
+	if perCommand {
+		cmd.Flags().StringVarP(&name, "command", "c", "", "Label or command line of a single run command")
+	}
+
+	return cmd
+}
+
+func logsCommand() *cobra.Command {
+	var (
+		follow bool
+		lines  int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "logs [schema]",
+		Short: "Show the recent output of the running eletrize",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			schema, err := schemaNumber(args)
+			if err != nil {
+				return err
+			}
+
+			response, err := send(control.Request{Action: control.ActionLogs, Schema: schema, Lines: lines})
+			if err != nil {
+				return err
+			}
+
+			prefix := len(response.Schemas) > 1
+			ends := make(map[int]int)
+
+			for _, schema := range response.Schemas {
+				printLogs(schema, prefix)
+				ends[schema.Number] = schema.OutputEnd
+			}
+
+			for follow {
+				time.Sleep(logsPollInterval)
+
+				for number, end := range ends {
+					response, err := send(control.Request{Action: control.ActionLogs, Schema: number, Since: end})
+					if err != nil {
+						return err
+					}
+
+					for _, schema := range response.Schemas {
+						printLogs(schema, prefix)
+						ends[schema.Number] = schema.OutputEnd
+					}
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "Keep printing the output as it is written")
+	cmd.Flags().IntVarP(&lines, "lines", "n", control.DefaultOutputLines, "Number of recent lines to show")
+
+	return cmd
+}
+
+func printLogs(schema control.SchemaStatus, prefix bool) {
+	for _, line := range schema.Output {
+		if prefix {
+			line = "[" + strings.TrimSpace(strconv.Itoa(schema.Number)+" "+schema.Label) + "] " + line
+		}
+
+		fmt.Println(line)
+	}
+}
@@ -0,0 +1,202 @@
+package command
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// buildCache keeps the outputs of the last successful builds of a schema,
+// keyed by the fingerprint of their inputs, so a build of inputs that were
+// already built, after switching branches or undoing an edit, is restored
+// instead of built again.
+type buildCache struct {
+	dir  string
+	size int
+}
+
+// newBuildCache returns the cache of the builds of output in workdir, kept
+// in the user cache directory.
+func newBuildCache(workdir, output string, size int) (*buildCache, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256([]byte(workdir + "\x00" + output))
+
+	return &buildCache{
+		dir:  filepath.Join(cacheDir, "eletrize", hex.EncodeToString(sum[:8])),
+		size: size,
+	}, nil
+}
+
+// restore copies the output cached for fingerprint to name, reporting
+// whether there was one.
+func (b *buildCache) restore(fingerprint, name string) bool {
+	cached := filepath.Join(b.dir, fingerprint)
+
+	if err := copyFile(cached, name); err != nil {
+		return false
+	}
+
+	// The most recently used outputs are the last ones to be evicted.
+	now := time.Now()
+	_ = os.Chtimes(cached, now, now)
+
+	return true
+}
+
+// store caches the output in name for fingerprint, evicting the least
+// recently used outputs beyond the size of the cache.
+func (b *buildCache) store(fingerprint, name string) error {
+	if err := os.MkdirAll(b.dir, 0o700); err != nil {
+		return err
+	}
+
+	if err := copyFile(name, filepath.Join(b.dir, fingerprint)); err != nil {
+		return err
+	}
+
+	b.evict()
+
+	return nil
+}
+
+func (b *buildCache) evict() {
+	entries, err := os.ReadDir(b.dir)
+	if err != nil {
+		return
+	}
+
+	type cached struct {
+		name    string
+		modTime time.Time
+	}
+
+	var outputs []cached
+
+	for _, entry := range entries {
+		if info, err := entry.Info(); err == nil && info.Mode().IsRegular() {
+			outputs = append(outputs, cached{entry.Name(), info.ModTime()})
+		}
+	}
+
+	slices.SortFunc(outputs, func(a, b cached) int {
+		return b.modTime.Compare(a.modTime)
+	})
+
+	for _, output := range outputs[min(b.size, len(outputs)):] {
+		_ = os.Remove(filepath.Join(b.dir, output.name))
+	}
+}
+
+// copyFile copies src to dst through a temporary file renamed over dst, so
+// a binary that is still running can be replaced.
+func copyFile(src, dst string) error {
+	source, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+
+	defer source.Close()
+
+	info, err := source.Stat()
+	if err != nil {
+		return err
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+"-*")
+	if err != nil {
+		return err
+	}
+
+	// The temporary file is left behind if eletrize is killed while copying.
+	recorder.AddArtifact(temp.Name())
+
+	defer func() {
+		_ = os.Remove(temp.Name())
+		recorder.RemoveArtifact(temp.Name())
+	}()
+
+	if _, err := io.Copy(temp, source); err != nil {
+		temp.Close()
+
+		return err
+	}
+
+	if err := temp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(temp.Name(), info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), dst)
+}
+
+// outputPattern returns the pattern matching the outputs of every build,
+// with the variables that change on each build replaced by "*".
+func (c *Commands) outputPattern() string {
+	vars := templateVars{"label": {c.label}, "workdir": {c.workdir}}
+
+	pattern := templateVarRegex.ReplaceAllString(vars.expand(c.Output), "*")
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(c.workdir, pattern)
+	}
+
+	return pattern
+}
+
+// perBuildOutput reports whether each build writes a new output, as when
+// output uses ${build_id}.
+func (c *Commands) perBuildOutput() bool {
+	return strings.Contains(c.outputPattern(), "*")
+}
+
+// hashFile returns the SHA-256 hash of the content of a file.
+func hashFile(name string) (string, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// buildFingerprint returns the fingerprint of a build of the inputs, which
+// also depends on the build steps and the output they write.
+// The build outputs and the cache itself are not inputs, even when they are
+// in the watched directory.
+func (c *Commands) buildFingerprint() (string, error) {
+	inputs, err := c.fingerprint(c.outputPattern(), filepath.Join(c.cache.dir, "*"))
+	if err != nil {
+		return "", err
+	}
+
+	config, err := json.Marshal(struct {
+		Build  Pipeline
+		Output string
+	}{c.Build, c.Output})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(append([]byte(inputs+"\x00"), config...))
+
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,132 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lasfh/eletrize/watcher"
+)
+
+func TestBuildCache(t *testing.T) {
+	dir := t.TempDir()
+	cache := &buildCache{dir: filepath.Join(dir, "cache"), size: 2}
+	output := filepath.Join(dir, "app")
+
+	build := func(fingerprint, content string) {
+		t.Helper()
+
+		if err := os.WriteFile(output, []byte(content), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := cache.store(fingerprint, output); err != nil {
+			t.Fatalf("store: %v", err)
+		}
+
+		// Keeps the modification times of the cached outputs apart.
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	build("a", "build a")
+	build("b", "build b")
+
+	if !cache.restore("a", output) {
+		t.Fatal("Expected build a to be restored")
+	}
+
+	content, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(content) != "build a" {
+		t.Errorf("Expected the output of build a, got %q", content)
+	}
+
+	if info, err := os.Stat(output); err != nil || info.Mode().Perm() != 0o755 {
+		t.Errorf("Expected the restored output to keep its mode, got %v (%v)", info.Mode(), err)
+	}
+
+	// Build a was used more recently than build b, which is evicted.
+	time.Sleep(10 * time.Millisecond)
+	build("c", "build c")
+
+	if cache.restore("b", output) {
+		t.Error("Expected build b to be evicted")
+	}
+
+	for _, fingerprint := range []string{"a", "c"} {
+		if !cache.restore(fingerprint, output) {
+			t.Errorf("Expected build %s to be cached", fingerprint)
+		}
+	}
+}
+
+func TestCommands_RestoresFromBuildCache(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	dir := t.TempDir()
+	builds := filepath.Join(t.TempDir(), "builds")
+
+	// Every file of dir is an input, including the output of the build.
+	w, err := watcher.NewWatcher(watcher.Options{Path: dir, Recursive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	c := &Commands{
+		Output:     "app",
+		BuildCache: 2,
+		Build: Pipeline{{Command: Command{
+			Method: "sh",
+			Args:   []string{"-c", "echo built >> " + builds + " && echo app > ${build_output}"},
+		}}},
+		Hooks: Hooks{
+			BeforeBuild: []Hook{{Command: Command{Method: "sh", Args: []string{"-c", "echo before >> " + builds}}}},
+			AfterBuild:  []Hook{{Command: Command{Method: "sh", Args: []string{"-c", "echo after >> " + builds}}}},
+		},
+		Run: []Command{{Method: "sleep", Args: []string{"30"}}},
+	}
+	c.SetFingerprint(w.Fingerprint)
+
+	if err := c.Start(nil, nil, dir); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	t.Cleanup(c.Quit)
+
+	waitFor(t, "Expected the first build to start the run commands", func() bool {
+		_, restarts := c.cycles.state()
+
+		return restarts == 1
+	})
+
+	c.SendEvent("main.go")
+
+	waitFor(t, "Expected the second build to restart the run commands", func() bool {
+		_, restarts := c.cycles.state()
+
+		return restarts == 2
+	})
+
+	content, err := os.ReadFile(builds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := strings.Count(string(content), "built"); n != 1 {
+		t.Errorf("Expected the second build to be restored from the cache, got %d builds", n)
+	}
+
+	// The hooks run around the restored build as well.
+	for _, hook := range []string{"before", "after"} {
+		if n := strings.Count(string(content), hook); n != 2 {
+			t.Errorf("Expected the %s_build hook to run twice, got %d", hook, n)
+		}
+	}
+}
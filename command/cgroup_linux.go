@@ -0,0 +1,286 @@
+package command
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const cgroupMount = "/sys/fs/cgroup"
+
+var cgroupControllers = []string{"cpu", "memory", "pids"}
+
+// cgroupTree is the cgroup v2 sub-tree created by eletrize, under which each
+// command with limits gets its own cgroup:
+//
+//	<cgroup of eletrize>/eletrize-<pid>/supervisor
+//	<cgroup of eletrize>/eletrize-<pid>/<n>-<command>
+//
+// A cgroup can only distribute memory and CPU to its children when it has no
+// processes of its own, so when eletrize is alone in its cgroup, as in a
+// delegated systemd scope, it moves itself into the supervisor cgroup.
+type cgroupTree struct {
+	mu          sync.Mutex
+	parent      string
+	dir         string
+	controllers []string
+	enabled     []string
+	supervisor  bool
+	count       int
+	err         error
+}
+
+var cgroups cgroupTree
+
+// newCgroup creates a cgroup for the named command with the given limits and
+// opens its directory.
+func newCgroup(name string, limits *Limits) (*os.File, error) {
+	cgroups.mu.Lock()
+	defer cgroups.mu.Unlock()
+
+	if cgroups.dir == "" && cgroups.err == nil {
+		cgroups.err = cgroups.create()
+	}
+
+	if cgroups.err != nil {
+		return nil, cgroups.err
+	}
+
+	cgroups.count++
+
+	dir := filepath.Join(cgroups.dir, fmt.Sprintf("%d-%s", cgroups.count, cgroupName(name)))
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("cgroup: %w", err)
+	}
+
+	file, err := configureCgroup(dir, limits)
+	if err != nil {
+		_ = os.Remove(dir)
+
+		return nil, fmt.Errorf("cgroup: %w", err)
+	}
+
+	return file, nil
+}
+
+func configureCgroup(dir string, limits *Limits) (*os.File, error) {
+	if limits.Memory > 0 {
+		if err := writeCgroupFile(dir, "memory.max", strconv.FormatUint(uint64(limits.Memory), 10)); err != nil {
+			return nil, err
+		}
+	}
+
+	if limits.CPU > 0 {
+		const period = 100000
+
+		quota := max(int(limits.CPU*period), 1000)
+		if err := writeCgroupFile(dir, "cpu.max", fmt.Sprintf("%d %d", quota, period)); err != nil {
+			return nil, err
+		}
+	}
+
+	if limits.Processes > 0 {
+		if err := writeCgroupFile(dir, "pids.max", strconv.FormatUint(limits.Processes, 10)); err != nil {
+			return nil, err
+		}
+	}
+
+	return os.Open(dir)
+}
+
+// create creates the sub-tree of eletrize and enables the controllers needed
+// by the limits.
+func (t *cgroupTree) create() error {
+	current, err := currentCgroup()
+	if err != nil {
+		return err
+	}
+
+	t.parent = filepath.Join(cgroupMount, current)
+
+	available, err := readCgroupList(t.parent, "cgroup.controllers")
+	if err != nil {
+		return fmt.Errorf("cgroup v2 is not available: %w", err)
+	}
+
+	dir := filepath.Join(t.parent, fmt.Sprintf("eletrize-%d", os.Getpid()))
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		return fmt.Errorf("cgroup: %w", err)
+	}
+
+	t.dir = dir
+
+	if err := t.enableControllers(available); err != nil {
+		t.release()
+
+		return fmt.Errorf("cgroup: cannot enable controllers in %s, run eletrize in a delegated cgroup: %w", t.parent, err)
+	}
+
+	if err := writeCgroupFile(t.dir, "cgroup.subtree_control", controllerChanges("+", t.controllers)); err != nil {
+		t.release()
+
+		return fmt.Errorf("cgroup: %w", err)
+	}
+
+	return nil
+}
+
+// enableControllers enables the controllers in the cgroup of eletrize. When
+// it has processes, eletrize moves itself to the supervisor cgroup and tries
+// again, which only succeeds if it was alone.
+func (t *cgroupTree) enableControllers(available []string) error {
+	active, err := readCgroupList(t.parent, "cgroup.subtree_control")
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+
+	for _, controller := range cgroupControllers {
+		if !slices.Contains(available, controller) {
+			continue
+		}
+
+		if !slices.Contains(active, controller) {
+			missing = append(missing, controller)
+		}
+
+		t.controllers = append(t.controllers, controller)
+	}
+
+	if len(t.controllers) == 0 {
+		return errors.New("no cpu, memory or pids controller available")
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	err = writeCgroupFile(t.parent, "cgroup.subtree_control", controllerChanges("+", missing))
+	if err == nil {
+		t.enabled = missing
+
+		return nil
+	}
+
+	supervisor := filepath.Join(t.dir, "supervisor")
+	if err := os.Mkdir(supervisor, 0o755); err != nil {
+		return err
+	}
+
+	t.supervisor = true
+
+	if err := writeCgroupFile(supervisor, "cgroup.procs", strconv.Itoa(os.Getpid())); err != nil {
+		return err
+	}
+
+	if err := writeCgroupFile(t.parent, "cgroup.subtree_control", controllerChanges("+", missing)); err != nil {
+		return err
+	}
+
+	t.enabled = missing
+
+	return nil
+}
+
+// release removes the sub-tree of eletrize, moving eletrize back to its
+// original cgroup. The cgroups of the commands must have been removed.
+func (t *cgroupTree) release() {
+	if t.dir == "" {
+		return
+	}
+
+	_ = writeCgroupFile(t.dir, "cgroup.subtree_control", controllerChanges("-", t.controllers))
+
+	if len(t.enabled) > 0 {
+		_ = writeCgroupFile(t.parent, "cgroup.subtree_control", controllerChanges("-", t.enabled))
+	}
+
+	if t.supervisor {
+		_ = writeCgroupFile(t.parent, "cgroup.procs", strconv.Itoa(os.Getpid()))
+		_ = os.Remove(filepath.Join(t.dir, "supervisor"))
+	}
+
+	_ = os.Remove(t.dir)
+
+	t.dir = ""
+	t.controllers = nil
+	t.enabled = nil
+	t.supervisor = false
+}
+
+// releaseCgroups removes the sub-tree created by eletrize, if any.
+func releaseCgroups() {
+	cgroups.mu.Lock()
+	defer cgroups.mu.Unlock()
+
+	cgroups.release()
+}
+
+// currentCgroup returns the cgroup v2 path of eletrize.
+func currentCgroup() (string, error) {
+	file, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if path, ok := strings.CutPrefix(scanner.Text(), "0::"); ok {
+			return path, nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	return "", errors.New("cgroup v2 is not available")
+}
+
+func readCgroupList(dir, name string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.Fields(string(data)), nil
+}
+
+func writeCgroupFile(dir, name, value string) error {
+	return os.WriteFile(filepath.Join(dir, name), []byte(value), 0o644)
+}
+
+func controllerChanges(sign string, controllers []string) string {
+	changes := make([]string, len(controllers))
+	for i, controller := range controllers {
+		changes[i] = sign + controller
+	}
+
+	return strings.Join(changes, " ")
+}
+
+// cgroupName turns the name of a command into a valid cgroup name.
+func cgroupName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		}
+
+		return '_'
+	}, name)
+
+	if len(name) > 64 {
+		name = name[:64]
+	}
+
+	return name
+}
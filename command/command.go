@@ -1,26 +1,78 @@
 package command
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"net"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/lasfh/eletrize/environments"
 	"github.com/lasfh/eletrize/output"
 )
 
+const (
+	// KindService is a long-running process, restarted on every change.
+	KindService = "service"
+	// KindTask runs to completion on every change, such as tests or linters.
+	KindTask = "task"
+)
+
 type Command struct {
-	Envs        environments.Envs `json:"envs" yaml:"envs"`
-	event       chan struct{}
-	quitHandler func()
-	Method      string   `json:"method" yaml:"method"`
-	EnvFile     string   `json:"env_file" yaml:"env_file"`
-	Args        []string `json:"args" yaml:"args"`
+	Envs          environments.Envs `json:"envs" yaml:"envs"`
+	tracker       *tracker
+	capture       io.Writer
+	vars          templateVars
+	listeners     []net.Listener
+	listenerFiles []*os.File
+	cgroup        *os.File
+	wrapper       []string
+	Limits        *Limits  `json:"limits" yaml:"limits"`
+	Label         string   `json:"label" yaml:"label"`
+	Kind          string   `json:"kind" yaml:"kind"`
+	Method        string   `json:"method" yaml:"method"`
+	Cmd           string   `json:"cmd" yaml:"cmd"`
+	Workdir       string   `json:"workdir" yaml:"workdir"`
+	EnvFile       string   `json:"env_file" yaml:"env_file"`
+	Args          []string `json:"args" yaml:"args"`
+	Listen        []string `json:"listen" yaml:"listen"`
+	IONice        string   `json:"ionice" yaml:"ionice"`
+	Nice          int      `json:"nice" yaml:"nice"`
+	Shell         bool     `json:"shell" yaml:"shell"`
+	Stdin         bool     `json:"stdin" yaml:"stdin"`
 }
 
 func (c *Command) isValidCommand() error {
+	if err := c.isValidPriority(); err != nil {
+		return err
+	}
+
+	if c.Stdin && errStdinUnsupported != nil {
+		return errStdinUnsupported
+	}
+
+	switch c.Kind {
+	case "", KindService:
+	case KindTask:
+		if len(c.Listen) > 0 {
+			return errors.New("listen: cannot be used by tasks")
+		}
+	default:
+		return fmt.Errorf("kind: %q is not %s or %s", c.Kind, KindService, KindTask)
+	}
+
+	if c.Cmd != "" {
+		if c.Method != "" || len(c.Args) > 0 {
+			return errors.New("cmd: cannot be combined with method and args")
+		}
+
+		return nil
+	}
+
 	if strings.TrimSpace(c.Method) == "" {
 		return fmt.Errorf("method: %w", ErrCommandIsEmpty)
 	}
@@ -28,7 +80,24 @@ func (c *Command) isValidCommand() error {
 	return nil
 }
 
-func (c *Command) prepareCommand(envs environments.Envs) error {
+func (c *Command) prepareCommand(envs environments.Envs, workdir string) error {
+	c.tracker = &tracker{}
+
+	if c.Workdir != "" {
+		if !filepath.IsAbs(c.Workdir) {
+			c.Workdir = filepath.Join(workdir, c.Workdir)
+		}
+
+		info, err := os.Stat(c.Workdir)
+		if err != nil {
+			return fmt.Errorf("workdir: %w", err)
+		}
+
+		if !info.IsDir() {
+			return fmt.Errorf("workdir: %s is not a directory", c.Workdir)
+		}
+	}
+
 	if c.Envs == nil && (envs != nil || c.EnvFile != "") {
 		c.Envs = make(environments.Envs)
 	}
@@ -43,46 +112,248 @@ func (c *Command) prepareCommand(envs environments.Envs) error {
 		}
 	}
 
-	c.event = make(chan struct{})
+	c.prepareLimits()
+
+	if len(c.Listen) > 0 && len(c.listeners) == 0 {
+		return c.openListeners()
+	}
+
+	return nil
+}
+
+func (c *Command) isValidPriority() error {
+	if c.Nice < -20 || c.Nice > 19 {
+		return fmt.Errorf("nice: %d is out of range, expected -20 to 19", c.Nice)
+	}
+
+	if c.IONice != "" {
+		if _, err := parseIONice(c.IONice); err != nil {
+			return err
+		}
+	}
+
+	if c.Limits != nil {
+		return c.Limits.isValidLimits()
+	}
 
 	return nil
 }
 
+func (c *Command) String() string {
+	if c.Cmd != "" {
+		return c.Cmd
+	}
+
+	return strings.Join(append([]string{c.Method}, c.Args...), " ")
+}
+
+// commandLine returns the executable and arguments used to start the process,
+// with the template variables expanded. Commands declared with cmd or shell
+// are interpreted by the shell, which enables pipes, redirection and
+// globbing.
+func (c *Command) commandLine() (string, []string) {
+	if c.Cmd != "" {
+		return shellCommand(c.vars.expandShell(c.Cmd))
+	}
+
+	if c.Shell {
+		words := make([]string, 0, len(c.Args)+1)
+		for _, word := range append([]string{c.Method}, c.Args...) {
+			words = append(words, c.vars.expandShell(word))
+		}
+
+		return shellCommand(strings.Join(words, " "))
+	}
+
+	return c.vars.expand(c.Method), c.vars.expandArgs(c.Args)
+}
+
+// name returns the label of the command or, when it has none, the command
+// line itself.
+func (c *Command) name() string {
+	if c.Label != "" {
+		return c.Label
+	}
+
+	return c.String()
+}
+
 func (c *Command) startProcess() error {
-	cmd := startProcess(c.Method, c.Args...)
-	cmd.Env = os.Environ()
-	cmd.Env = append(cmd.Env, c.Envs.Variables()...)
+	return c.startProcessContext(context.Background())
+}
+
+// startProcessContext starts the command and waits for it to finish. The
+// process is stopped if ctx is done before it exits.
+func (c *Command) startProcessContext(ctx context.Context) error {
+	p, err := c.start()
+	if err != nil {
+		return err
+	}
+
+	stop := context.AfterFunc(ctx, p.stop)
+	defer stop()
+
+	_, err = c.wait(p)
+
+	return err
+}
+
+// start starts the process of the command without waiting for it.
+func (c *Command) start() (*process, error) {
+	env := os.Environ()
+
+	for _, variable := range c.Envs.Variables() {
+		env = append(env, c.vars.expand(variable))
+	}
+
+	name, args := c.commandLine()
+	wrapper, args := c.withWrapper(name, args)
+	wrapper, args, listenEnvs := c.inheritListeners(wrapper, args)
+
+	// A wrapper starts even when the executable of the command is missing,
+	// which would only be noticed by its exit status.
+	if wrapper != name {
+		if err := c.lookPath(name, env); err != nil {
+			err = c.startError(name, pathOf(env), err)
+			c.tracker.fail(err)
+
+			return nil, err
+		}
+	}
+
+	cmd := startProcess(wrapper, args...)
+	cmd.Dir = c.Workdir
+	cmd.Env = append(env, listenEnvs...)
+	cmd.ExtraFiles = c.listenerFiles
+	c.configureLimits(cmd)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
-	if err := cmd.Start(); err != nil {
-		log.Fatalln(err)
+	if c.capture != nil {
+		cmd.Stdout = io.MultiWriter(os.Stdout, c.capture)
+		cmd.Stderr = io.MultiWriter(os.Stderr, c.capture)
 	}
 
-	c.watchEventKill(cmd)
-	c.quitHandler = func() {
-		_ = KillProcess(cmd)
+	// The output is copied from the terminal of a stdin command.
+	out := cmd.Stdout
+
+	var term *terminal
+
+	if c.Stdin {
+		var err error
+		if term, err = openTerminal(cmd); err != nil {
+			c.tracker.fail(err)
+
+			return nil, err
+		}
+	}
+
+	if err := startCommand(cmd); err != nil {
+		if term != nil {
+			term.close()
+		}
+
+		err = c.startError(name, os.Getenv("PATH"), err)
+		c.tracker.fail(err)
+
+		return nil, err
 	}
 
-	return cmd.Wait()
+	recorder.AddProcess(cmd.Process.Pid, c.name())
+
+	if term != nil {
+		term.started(out)
+	}
+
+	p := newProcess(c.name(), cmd)
+	if term != nil {
+		p.input = term.pty
+	}
+
+	c.tracker.set(p)
+
+	return p, nil
 }
 
-func (c *Command) waitToStart() {
+func (c *Command) wait(p *process) (exit, error) {
+	status, err := p.wait()
+	c.tracker.exited(p, status, err)
+
+	return status, err
+}
+
+// startService starts a run command and reports, under label, how its process
+// exits.
+func (c *Command) startService(label *output.Label) error {
+	p, err := c.start()
+	if err != nil {
+		return err
+	}
+
 	go func() {
-		<-c.event
+		status, _ := c.wait(p)
 
-		c.startProcess()
+		output.Pushf(label, "%s: %s\n", c.name(), status)
 	}()
+
+	return nil
 }
 
-func (c *Command) watchEventKill(cmd *exec.Cmd) {
+// startTask starts a task and reports, under label, whether it passes or
+// fails once it finishes. The task is canceled if ctx is done before then.
+func (c *Command) startTask(ctx context.Context, label *output.Label) error {
+	p, err := c.start()
+	if err != nil {
+		return err
+	}
+
+	stop := context.AfterFunc(ctx, p.stop)
+
 	go func() {
-		<-c.event
+		defer stop()
 
-		if err := KillProcess(cmd); err != nil {
-			output.Pushf(output.LabelEletrize, "ERROR MESSAGE WHEN KILLING PROCESS: %s\n", err)
-		}
+		status, err := c.wait(p)
 
-		c.startProcess()
+		switch {
+		case status.stopping:
+			output.Pushf(label, "TASK %s: CANCELED after %s\n", c.name(), status.uptime.Round(time.Millisecond))
+		case err != nil:
+			output.Pushf(label, "TASK %s: FAIL (%s)\n", c.name(), status)
+		default:
+			output.Pushf(label, "TASK %s: PASS (%s)\n", c.name(), status.uptime.Round(time.Millisecond))
+		}
 	}()
+
+	return nil
+}
+
+// startOnDemand starts the command outside of a reload cycle. A task runs
+// until it finishes or the next cycle stops it.
+func (c *Command) startOnDemand(label *output.Label) error {
+	if c.isTask() {
+		return c.startTask(context.Background(), label)
+	}
+
+	return c.startService(label)
+}
+
+func (c *Command) isTask() bool {
+	return c.Kind == KindTask
+}
+
+// stopProcess stops the running process of the command and reports whether
+// there was one.
+func (c *Command) stopProcess() bool {
+	p := c.tracker.get()
+	if p == nil {
+		return false
+	}
+
+	p.stop()
+
+	return true
+}
+
+func (c *Command) isRunning() bool {
+	return c.tracker.get() != nil
 }
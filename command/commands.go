@@ -1,9 +1,13 @@
 package command
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"slices"
 	"time"
 
 	"github.com/lasfh/eletrize/environments"
@@ -16,96 +20,261 @@ var (
 )
 
 type Commands struct {
-	Build                *Command  `json:"build" yaml:"build"`
-	Run                  []Command `json:"run" yaml:"run"`
-	Clean                []string  `json:"-"`
-	debounceEventHandler func()
-	labelBuild           *output.Label
+	Build                   Pipeline  `json:"build" yaml:"build"`
+	Run                     []Command `json:"run" yaml:"run"`
+	Hooks                   Hooks     `json:"hooks" yaml:"hooks"`
+	Output                  string    `json:"output" yaml:"output"`
+	KeepAliveOnBuildFailure bool      `json:"keep_alive_on_build_failure" yaml:"keep_alive_on_build_failure"`
+	BuildCache              int       `json:"build_cache" yaml:"build_cache"`
+	RestartOnOutputChange   bool      `json:"restart_on_output_change" yaml:"restart_on_output_change"`
+	Clean                   []string  `json:"-"`
+	debounceEventHandler    func()
+	labelBuild              *output.Label
+	labelHook               *output.Label
+	labelRun                *output.Label
+	labelWatcher            *output.Label
+	cycles                  *cycles
+	changes                 *changes
+	subscribers             []func(Event)
+	label                   string
+	workdir                 string
+	recent                  *recentOutput
+	fingerprint             func(exclude ...string) (string, error)
+	cache                   *buildCache
+	lastOutput              string
+	runningVars             templateVars
+	runningHash             string
 }
 
 func (c *Commands) Start(
 	label *output.Label,
 	envs environments.Envs,
+	workdir string,
 ) error {
 	if err := c.isValidCommands(); err != nil {
 		return err
 	}
 
-	if err := c.prepareCommands(envs); err != nil {
+	if err := c.prepareCommands(envs, workdir); err != nil {
 		return err
 	}
 
+	if c.BuildCache > 0 && c.fingerprint != nil {
+		cache, err := newBuildCache(workdir, c.Output, c.BuildCache)
+		if err != nil {
+			return fmt.Errorf("build_cache: %w", err)
+		}
+
+		c.cache = cache
+	}
+
+	enableSubreaper()
+
+	for i := range c.Clean {
+		if !filepath.IsAbs(c.Clean[i]) {
+			c.Clean[i] = filepath.Join(workdir, c.Clean[i])
+		}
+
+		recorder.AddArtifact(c.Clean[i])
+	}
+
+	c.cycles = &cycles{}
+	c.changes = &changes{}
+	c.recent = &recentOutput{}
+
+	for i := range c.Run {
+		c.Run[i].capture = c.recent
+	}
+	c.workdir = workdir
 	c.debounceEventHandler = debounce(800*time.Millisecond, c.cancelProcesses)
 	c.labelBuild = output.LabelBuild.Sub(label)
+	c.labelHook = output.LabelHook.Sub(label)
+	c.labelRun = output.LabelRun.Sub(label)
+	c.labelWatcher = output.LabelWatcher.Sub(label)
+
+	if label != nil {
+		c.label = label.Label
+	}
 
-	c.startProcesses()
+	c.cancelProcesses()
 
 	return nil
 }
 
-func (c *Commands) SendEvent() {
-	c.debounceEventHandler()
+// SetFingerprint sets the function that fingerprints the inputs of the
+// build, leaving out the files matching the exclude patterns, which keys
+// the outputs kept by build_cache. It must be called before Start.
+func (c *Commands) SetFingerprint(fn func(exclude ...string) (string, error)) {
+	c.fingerprint = fn
+}
+
+// SendEvent schedules a reload cycle after the named file changed. The
+// files changed since the last cycle are available to the commands through
+// the changed_file, changed_files and changed_pkg template variables.
+func (c *Commands) SendEvent(name string) {
+	if name != "" {
+		c.changes.add(relativeFile(c.workdir, name))
+	}
+
+	if !c.changes.isPaused() {
+		c.debounceEventHandler()
+	}
+}
+
+// Rebuild runs a reload cycle without waiting for a file change.
+func (c *Commands) Rebuild() {
+	go c.reload(errRebuild, true)
+}
+
+// Restart restarts the run commands without building them again.
+func (c *Commands) Restart() {
+	go c.reload(errRestart, false)
+}
+
+// TogglePause pauses or resumes the reload cycles triggered by file changes.
+// The files changed while paused are reloaded once resumed.
+func (c *Commands) TogglePause() {
+	paused, changed := c.changes.togglePause()
+	if paused {
+		output.Pushf(c.labelWatcher, "PAUSED\n")
+
+		return
+	}
+
+	output.Pushf(c.labelWatcher, "RESUMED\n")
+
+	if changed {
+		c.debounceEventHandler()
+	}
 }
 
 func (c *Commands) Quit() {
-	if c.Build != nil && c.Build.quitHandler != nil {
-		c.Build.quitHandler()
+	if c.cycles != nil {
+		c.cycles.close()
+	}
+
+	c.Build.quit()
+	c.Hooks.quit()
+
+	// The cycle in progress may still start the run commands, so they are
+	// only stopped once it ends.
+	if c.cycles != nil {
+		c.cycles.running.Lock()
+		defer c.cycles.running.Unlock()
 	}
 
+	if c.stopProcesses() {
+		_ = runHooks(context.Background(), c.labelHook, "after_stop", c.Hooks.AfterStop)
+	}
+
+	_ = runHooks(context.Background(), c.labelHook, "on_exit", c.Hooks.OnExit)
+
+	killOrphans()
+
 	for i := range c.Run {
-		if c.Run[i].quitHandler != nil {
-			c.Run[i].quitHandler()
-		}
+		c.Run[i].closeListeners()
+		c.Run[i].releaseLimits()
 	}
 
+	c.Build.releaseLimits()
+	c.Hooks.releaseLimits()
+	releaseCgroups()
+
 	for i := range c.Clean {
 		_ = os.Remove(c.Clean[i])
+		recorder.RemoveArtifact(c.Clean[i])
+	}
+
+	if c.lastOutput != "" && c.perBuildOutput() {
+		removeOutput(c.lastOutput)
 	}
 }
 
 func (c *Commands) isValidCommands() error {
-	if c.Build != nil {
-		if err := c.Build.isValidCommand(); err != nil {
-			return fmt.Errorf("build: %w", err)
-		}
+	if err := c.Build.isValidPipeline(); err != nil {
+		return fmt.Errorf("build%w", err)
 	}
 
 	if len(c.Run) == 0 {
 		return ErrRunCommandsIsEmpty
 	}
 
+	var stdin int
+
 	for i := range c.Run {
 		if err := c.Run[i].isValidCommand(); err != nil {
 			return fmt.Errorf("run[%d]: %w", i, err)
 		}
+
+		if c.Run[i].Stdin {
+			stdin++
+		}
+	}
+
+	if stdin > 1 {
+		return errors.New("run: stdin can only be set on one command")
+	}
+
+	if err := c.Hooks.isValidHooks(); err != nil {
+		return fmt.Errorf("hooks: %w", err)
+	}
+
+	if c.BuildCache < 0 {
+		return errors.New("build_cache: cannot be negative")
+	}
+
+	if c.BuildCache > 0 && (c.Output == "" || len(c.Build) == 0) {
+		return errors.New("build_cache: requires build and output")
+	}
+
+	if c.RestartOnOutputChange && (c.Output == "" || len(c.Build) == 0) {
+		return errors.New("restart_on_output_change: requires build and output")
 	}
 
 	return nil
 }
 
-func (c *Commands) prepareCommands(envs environments.Envs) error {
-	if c.Build != nil {
-		if err := c.Build.prepareCommand(envs); err != nil {
-			return err
-		}
+func (c *Commands) prepareCommands(envs environments.Envs, workdir string) error {
+	if err := c.Build.preparePipeline(envs, workdir); err != nil {
+		return fmt.Errorf("build%w", err)
 	}
 
 	for i := range c.Run {
-		if err := c.Run[i].prepareCommand(envs); err != nil {
-			return err
+		if err := c.Run[i].prepareCommand(envs, workdir); err != nil {
+			return fmt.Errorf("run[%d]: %w", i, err)
 		}
 	}
 
+	if err := c.Hooks.prepareHooks(envs, workdir); err != nil {
+		return fmt.Errorf("hooks: %w", err)
+	}
+
 	return nil
 }
 
-func (c *Commands) ifPresentRunBuild() error {
-	if c.Build != nil {
+// ifPresentRunBuild runs the build between its hooks, unless restore brings
+// back the output of a previous build.
+func (c *Commands) ifPresentRunBuild(ctx context.Context, capture io.Writer, restore func() bool) error {
+	if err := runHooks(ctx, c.labelHook, "before_build", c.Hooks.BeforeBuild); err != nil {
+		return err
+	}
+
+	if restore() {
+		return runHooks(ctx, c.labelHook, "after_build", c.Hooks.AfterBuild)
+	}
+
+	if len(c.Build) > 0 {
 		output.Push(c.labelBuild, "PROCESSING... ")
 
 		startTime := time.Now()
 
-		if err := c.Build.startProcess(); err != nil {
+		if err := c.Build.run(ctx, c.labelBuild, capture); err != nil {
+			if ctx.Err() != nil {
+				output.Pushf(c.labelBuild, "CANCELED: %s\n", context.Cause(ctx))
+
+				return err
+			}
+
 			output.Pushf(c.labelBuild, "FAILED: %s\n", err)
 
 			return err
@@ -118,35 +287,379 @@ func (c *Commands) ifPresentRunBuild() error {
 		)
 	}
 
-	return nil
+	return runHooks(ctx, c.labelHook, "after_build", c.Hooks.AfterBuild)
 }
 
+// cancelProcesses runs the reload cycle of the changed files.
 func (c *Commands) cancelProcesses() {
-	if err := c.ifPresentRunBuild(); err != nil {
-		for i := range c.Run {
-			if c.Run[i].quitHandler != nil {
-				c.Run[i].quitHandler()
+	c.reload(errNewChanges, true)
+}
+
+// reload runs a reload cycle: it builds the project, when build is set, and
+// restarts the run commands. A newer cycle cancels the previous one with the
+// given cause and cycles never run concurrently.
+func (c *Commands) reload(cause error, build bool) {
+	ctx := c.cycles.next(cause)
+
+	c.cycles.running.Lock()
+	defer c.cycles.running.Unlock()
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	if !build {
+		defer c.resumeChanges()
+	}
+
+	var (
+		vars templateVars
+		hash string
+	)
+
+	if build {
+		files := c.changes.take()
+		vars = c.cycleVars(files)
+		c.setVars(vars)
+
+		if c.perBuildOutput() {
+			recorder.AddArtifact(vars["build_output"][0])
+		}
+
+		// A rebuild is requested when something the inputs do not cover
+		// changed, so it is never restored from the cache.
+		if !c.build(ctx, files, vars, !errors.Is(cause, errRebuild)) {
+			c.discardOutput(vars)
+
+			return
+		}
+
+		// The cycle was canceled after its build succeeded.
+		if ctx.Err() != nil {
+			c.changes.add(files...)
+			c.discardOutput(vars)
+
+			return
+		}
+
+		if c.RestartOnOutputChange {
+			hash = c.outputHash(vars)
+
+			if c.outputUnchanged(vars, hash) {
+				output.Pushf(c.labelBuild, "OUTPUT UNCHANGED, RESTART SKIPPED\n")
+				c.restartTasks(ctx)
+				c.emit(Event{Type: EventUnchanged})
+
+				return
 			}
 		}
+	}
+
+	if c.stopProcesses() {
+		c.emit(Event{Type: EventStopped})
+
+		if err := runHooks(context.Background(), c.labelHook, "after_stop", c.Hooks.AfterStop); err != nil {
+			c.emit(Event{Type: EventBuildFailed, Err: err})
+
+			return
+		}
+	}
+
+	err := c.startProcesses(ctx)
+	c.cycles.restarted()
+
+	if err != nil {
+		c.emit(Event{Type: EventBuildFailed, Err: err})
 
 		return
 	}
 
+	if build {
+		c.removeLastOutput(vars)
+		c.runningVars = vars
+		c.runningHash = hash
+	}
+
+	c.emit(Event{Type: EventStarted})
+}
+
+// resumeChanges schedules the reload cycle of the files changed before a
+// restart, which were carried over when the restart canceled their build.
+func (c *Commands) resumeChanges() {
+	if c.changes.pending() && !c.changes.isPaused() {
+		c.debounceEventHandler()
+	}
+}
+
+// build builds the project, or restores it from the build cache when
+// cached is set, reporting whether the run commands should be restarted.
+func (c *Commands) build(ctx context.Context, files []string, vars templateVars, cached bool) bool {
+	c.emit(Event{Type: EventBuildStarted})
+
+	c.cycles.setBuilding(true)
+	defer c.cycles.setBuilding(false)
+
+	var fingerprint string
+
+	if c.cache != nil {
+		var err error
+
+		fingerprint, err = c.buildFingerprint()
+		if err != nil {
+			output.Pushf(c.labelBuild, "FAILED TO FINGERPRINT THE INPUTS: %s\n", err)
+		}
+	}
+
+	var restored bool
+
+	restore := func() bool {
+		restored = cached && fingerprint != "" && c.cache.restore(fingerprint, vars["build_output"][0])
+		if restored {
+			output.Pushf(c.labelBuild, "RESTORED FROM CACHE (%s)\n", fingerprint[:12])
+		}
+
+		return restored
+	}
+
+	var buildOutput outputBuffer
+
+	if err := c.ifPresentRunBuild(ctx, io.MultiWriter(&buildOutput, c.recent), restore); err != nil {
+		if ctx.Err() != nil {
+			// The changes are carried over to the cycle that canceled this one.
+			c.changes.add(files...)
+
+			return false
+		}
+
+		keptAlive := c.KeepAliveOnBuildFailure && c.isRunning()
+
+		c.emit(Event{Type: EventBuildFailed, Err: err, Output: buildOutput.String(), KeptAlive: keptAlive})
+
+		if keptAlive {
+			output.Alertf(c.labelBuild, " BUILD FAILED - the previous version is still running ")
+
+			return false
+		}
+
+		if c.stopProcesses() {
+			c.emit(Event{Type: EventStopped})
+
+			_ = runHooks(context.Background(), c.labelHook, "after_stop", c.Hooks.AfterStop)
+		}
+
+		return false
+	}
+
+	// The inputs that changed during the build may not be in its output.
+	if fingerprint != "" && !restored {
+		if current, err := c.buildFingerprint(); err == nil && current == fingerprint {
+			if err := c.cache.store(fingerprint, vars["build_output"][0]); err != nil {
+				output.Pushf(c.labelBuild, "FAILED TO CACHE THE OUTPUT: %s\n", err)
+			}
+		}
+	}
+
+	return true
+}
+
+// AcceptsInput reports whether a run command reads the terminal input.
+func (c *Commands) AcceptsInput() bool {
+	return slices.ContainsFunc(c.Run, func(command Command) bool {
+		return command.Stdin
+	})
+}
+
+// SendInput writes the input typed in the terminal to the run command
+// declared with stdin, when it is running.
+func (c *Commands) SendInput(input []byte) {
 	for i := range c.Run {
-		c.Run[i].event <- struct{}{}
+		if !c.Run[i].Stdin {
+			continue
+		}
+
+		if p := c.Run[i].tracker.get(); p != nil && p.input != nil {
+			_, _ = p.input.Write(input)
+		}
 	}
 }
 
-func (c *Commands) startProcesses() {
-	if err := c.ifPresentRunBuild(); err != nil {
-		for i := range c.Run {
-			c.Run[i].waitToStart()
+// setVars sets the template variables of the cycle on every command.
+func (c *Commands) setVars(vars templateVars) {
+	for i := range c.Build {
+		c.Build[i].vars = vars
+	}
+
+	for _, phase := range c.Hooks.phases() {
+		for i := range phase.hooks {
+			phase.hooks[i].vars = vars
 		}
+	}
 
+	for i := range c.Run {
+		c.Run[i].vars = vars
+	}
+}
+
+// removeLastOutput removes the build output of the previous cycle once the
+// commands are running the new one, when the output path changes on every
+// build, such as "bin/app-${build_id}".
+func (c *Commands) removeLastOutput(vars templateVars) {
+	if len(vars["build_output"]) == 0 {
 		return
 	}
 
+	current := vars["build_output"][0]
+
+	if c.lastOutput != "" && c.lastOutput != current {
+		removeOutput(c.lastOutput)
+	}
+
+	c.lastOutput = current
+}
+
+// discardOutput removes the output of a cycle whose run commands were not
+// restarted, when each build writes a new one.
+func (c *Commands) discardOutput(vars templateVars) {
+	if current := vars["build_output"]; c.perBuildOutput() && current[0] != c.lastOutput {
+		removeOutput(current[0])
+	}
+}
+
+func removeOutput(name string) {
+	_ = os.Remove(name)
+	recorder.RemoveArtifact(name)
+}
+
+// outputHash returns the hash of the build output of the cycle, or an empty
+// string when it cannot be read.
+func (c *Commands) outputHash(vars templateVars) string {
+	hash, err := hashFile(vars["build_output"][0])
+	if err != nil {
+		output.Pushf(c.labelBuild, "FAILED TO HASH THE OUTPUT: %s\n", err)
+
+		return ""
+	}
+
+	return hash
+}
+
+// outputUnchanged reports whether the build output of the cycle is the one
+// the run commands are running, which are then kept running. The cycle
+// gives way to the previous one: its variables are replaced and its output
+// removed, when they differ. The tasks keep the changed files of the cycle,
+// since they run again.
+func (c *Commands) outputUnchanged(vars templateVars, hash string) bool {
+	if hash == "" || hash != c.runningHash || !c.servicesRunning() {
+		return false
+	}
+
+	if current := vars["build_output"][0]; current != c.lastOutput {
+		removeOutput(current)
+	}
+
+	c.setVars(c.runningVars)
+
+	vars["build_output"] = c.runningVars["build_output"]
+
+	for i := range c.Run {
+		if c.Run[i].isTask() {
+			c.Run[i].vars = vars
+		}
+	}
+
+	return true
+}
+
+// servicesRunning reports whether every service not stopped through the
+// control API is running.
+func (c *Commands) servicesRunning() bool {
+	for i := range c.Run {
+		if !c.Run[i].isTask() && !c.Run[i].tracker.isStopped() && !c.Run[i].isRunning() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// stopProcesses stops the run commands and kills the orphaned processes left
+// behind by them, reporting whether any service was running.
+func (c *Commands) stopProcesses() bool {
+	var stopped bool
+
+	for i := range c.Run {
+		if c.Run[i].stopProcess() && !c.Run[i].isTask() {
+			stopped = true
+		}
+	}
+
+	killOrphans()
+
+	return stopped
+}
+
+// isRunning reports whether any service is running.
+func (c *Commands) isRunning() bool {
 	for i := range c.Run {
-		go c.Run[i].startProcess()
+		if !c.Run[i].isTask() && c.Run[i].isRunning() {
+			return true
+		}
 	}
+
+	return false
+}
+
+// startProcesses starts the services and the tasks of the run commands. The
+// tasks are canceled when ctx is done, as the next cycle begins.
+func (c *Commands) startProcesses(ctx context.Context) error {
+	if err := runHooks(context.Background(), c.labelHook, "before_run", c.Hooks.BeforeRun); err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for i := range c.Run {
+		if c.Run[i].tracker.isStopped() {
+			continue
+		}
+
+		if err := c.startRun(ctx, i); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// restartTasks runs the tasks again when the services keep running, since
+// the cycle canceled them.
+func (c *Commands) restartTasks(ctx context.Context) {
+	for i := range c.Run {
+		if !c.Run[i].isTask() || c.Run[i].tracker.isStopped() {
+			continue
+		}
+
+		c.Run[i].stopProcess()
+		_ = c.startRun(ctx, i)
+	}
+}
+
+// startRun starts the run command at index i, reporting when it fails to
+// start.
+func (c *Commands) startRun(ctx context.Context, i int) error {
+	start := c.Run[i].startService
+	if c.Run[i].isTask() {
+		start = func(label *output.Label) error {
+			return c.Run[i].startTask(ctx, label)
+		}
+	}
+
+	if err := start(c.labelRun); err != nil {
+		output.Alertf(c.labelRun, " %s: FAILED TO START ", c.Run[i].name())
+		output.Pushf(c.labelRun, "%s\n", err)
+
+		return fmt.Errorf("%s: %w", c.Run[i].name(), err)
+	}
+
+	return nil
 }
@@ -0,0 +1,278 @@
+package command
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCommands_OutputUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	running := filepath.Join(dir, "app-1")
+	rebuilt := filepath.Join(dir, "app-2")
+
+	for _, name := range []string{running, rebuilt} {
+		if err := os.WriteFile(name, []byte("binary"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hash, err := hashFile(running)
+	if err != nil {
+		t.Fatalf("hashFile: %v", err)
+	}
+
+	runningVars := templateVars{"build_output": {running}}
+	c := &Commands{
+		Run:         []Command{{Kind: KindTask, tracker: &tracker{}}},
+		lastOutput:  running,
+		runningVars: runningVars,
+		runningHash: hash,
+	}
+
+	vars := templateVars{"build_output": {rebuilt}}
+
+	if c.outputUnchanged(vars, "other") {
+		t.Fatal("Expected a different hash to restart the run commands")
+	}
+
+	if !c.outputUnchanged(vars, hash) {
+		t.Fatal("Expected the same hash to keep the run commands running")
+	}
+
+	if _, err := os.Stat(rebuilt); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the unused output to be removed, got %v", err)
+	}
+
+	if _, err := os.Stat(running); err != nil {
+		t.Errorf("Expected the running output to be kept, got %v", err)
+	}
+
+	if got := c.Run[0].vars["build_output"][0]; got != running {
+		t.Errorf("Expected the variables of the running cycle, got %q", got)
+	}
+}
+
+func TestCommands_UnchangedOutputRestartsTasks(t *testing.T) {
+	c := &Commands{
+		Output:                "app",
+		RestartOnOutputChange: true,
+		Build: Pipeline{{Command: Command{
+			Method: "sh",
+			Args:   []string{"-c", "echo same > ${build_output}"},
+		}}},
+		Run: []Command{
+			{Method: "sleep", Args: []string{"30"}},
+			{Kind: KindTask, Method: "sh", Args: []string{"-c", "echo ${changed_files} >> ${workdir}/tasks"}},
+		},
+	}
+
+	dir := startCommands(t, c)
+	service := c.Run[0].tracker.get()
+
+	c.SendEvent("main.go")
+
+	waitFor(t, "Expected the task to run on the changes of main.go", func() bool {
+		tasks, _ := os.ReadFile(filepath.Join(dir, "tasks"))
+
+		return strings.Contains(string(tasks), "main.go")
+	})
+
+	if c.Run[0].tracker.get() != service {
+		t.Error("Expected the service to keep running")
+	}
+}
+
+func TestCommands_RestartCarriesOverChanges(t *testing.T) {
+	c := &Commands{
+		Build: Pipeline{{Command: Command{
+			Method: "sh",
+			Args:   []string{"-c", "sleep 0.5 && echo ${changed_files} >> ${workdir}/built"},
+		}}},
+		Run: []Command{{Method: "sleep", Args: []string{"30"}}},
+	}
+
+	dir := startCommands(t, c)
+
+	c.SendEvent("main.go")
+
+	// The restart cancels the build of main.go, which must not be lost.
+	waitFor(t, "Expected the build of main.go to start", func() bool {
+		building, _ := c.cycles.state()
+
+		return building
+	})
+
+	c.Restart()
+
+	waitFor(t, "Expected main.go to be built after the restart", func() bool {
+		built, _ := os.ReadFile(filepath.Join(dir, "built"))
+
+		return strings.Contains(string(built), "main.go")
+	})
+}
+
+func TestCommands_QuitDuringReload(t *testing.T) {
+	// Quits before and during the build of a reload cycle, and once it
+	// restarted the run commands.
+	tests := []struct {
+		name  string
+		state func(c *Commands) bool
+	}{
+		{"Before the build", func(c *Commands) bool {
+			return true
+		}},
+		{"During the build", func(c *Commands) bool {
+			building, _ := c.cycles.state()
+
+			return building
+		}},
+		{"After the restart", func(c *Commands) bool {
+			_, restarts := c.cycles.state()
+
+			return restarts == 2
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Commands{
+				Build: Pipeline{{Command: Command{Method: "sh", Args: []string{"-c", "sleep 0.3"}}}},
+				Run:   []Command{{Method: "sleep", Args: []string{"30"}}},
+			}
+
+			startCommands(t, c)
+
+			c.Rebuild()
+			waitFor(t, "Expected the reload cycle to reach the state", func() bool {
+				return tt.state(c)
+			})
+			c.Quit()
+
+			if c.isRunning() {
+				t.Fatal("Expected the run commands to be stopped after quitting")
+			}
+
+			// A cycle cannot start them after Quit.
+			c.reload(errRestart, false)
+
+			if c.isRunning() {
+				t.Fatal("Expected the run commands to stay stopped after quitting")
+			}
+		})
+	}
+}
+
+func TestCommands_RecordsPerBuildOutputs(t *testing.T) {
+	artifacts := &recordedArtifacts{}
+
+	defer RecordSession(recorder)
+	RecordSession(artifacts)
+
+	c := &Commands{
+		Output: "app-${build_id}",
+		Build: Pipeline{{Command: Command{
+			Method: "sh",
+			Args:   []string{"-c", "echo app > ${build_output}"},
+		}}},
+		Run: []Command{{Method: "sleep", Args: []string{"30"}}},
+	}
+
+	startCommands(t, c)
+
+	c.Rebuild()
+
+	waitFor(t, "Expected the rebuild to restart the run commands", func() bool {
+		_, restarts := c.cycles.state()
+
+		return restarts == 2
+	})
+
+	// Only the output of the running build is left.
+	waitFor(t, "Expected the output of the previous build to be removed", func() bool {
+		return len(artifacts.list()) == 1
+	})
+
+	running := artifacts.list()[0]
+
+	c.Quit()
+
+	if running != c.lastOutput {
+		t.Errorf("Expected the running output %s to be recorded, got %s", c.lastOutput, running)
+	}
+
+	if got := artifacts.list(); len(got) != 0 {
+		t.Errorf("Expected no artifact after quitting, got %q", got)
+	}
+
+	if _, err := os.Stat(c.lastOutput); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the last output to be removed, got %v", err)
+	}
+}
+
+// recordedArtifacts records the artifacts of the commands.
+type recordedArtifacts struct {
+	noRecorder
+
+	mu        sync.Mutex
+	artifacts []string
+}
+
+func (r *recordedArtifacts) AddArtifact(path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.artifacts = append(r.artifacts, path)
+}
+
+func (r *recordedArtifacts) RemoveArtifact(path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.artifacts = slices.DeleteFunc(r.artifacts, func(artifact string) bool {
+		return artifact == path
+	})
+}
+
+func (r *recordedArtifacts) list() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.artifacts)
+}
+
+// startCommands starts c in a temporary directory, stopping it at the end
+// of the test.
+func startCommands(t *testing.T, c *Commands) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	if err := c.Start(nil, nil, dir); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	t.Cleanup(c.Quit)
+
+	return dir
+}
+
+// waitFor waits until cond holds, failing the test after a few seconds.
+func waitFor(t *testing.T, message string, cond func() bool) {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		if cond() {
+			return
+		}
+
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	t.Fatal(message)
+}
@@ -0,0 +1,90 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	errNewChanges = errors.New("new changes detected")
+	errRebuild    = errors.New("rebuild requested")
+	errRestart    = errors.New("restart requested")
+	errQuit       = errors.New("eletrize is exiting")
+)
+
+// cycles coordinates the reload cycles of a set of commands, making sure a
+// newer cycle cancels the previous one and that they never run concurrently.
+type cycles struct {
+	running  sync.Mutex
+	mu       sync.Mutex
+	cancel   context.CancelCauseFunc
+	closed   bool
+	building bool
+	restarts int
+}
+
+// next cancels the cycle in progress with the given cause, killing its
+// build, and returns the context of the new cycle.
+func (c *cycles) next(cause error) context.Context {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cancel != nil {
+		c.cancel(cause)
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	if c.closed {
+		cancel(errQuit)
+	}
+
+	c.cancel = cancel
+
+	return ctx
+}
+
+// close cancels the cycle in progress when eletrize is exiting, and makes
+// the later cycles start canceled.
+func (c *cycles) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+
+	if c.cancel != nil {
+		c.cancel(errQuit)
+	}
+}
+
+func (c *cycles) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.closed
+}
+
+func (c *cycles) setBuilding(building bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.building = building
+}
+
+// restarted counts a restart of the run commands.
+func (c *cycles) restarted() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.restarts++
+}
+
+// state reports whether a build is in progress and how many times the run
+// commands have been restarted.
+func (c *cycles) state() (building bool, restarts int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.building, c.restarts
+}
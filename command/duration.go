@@ -0,0 +1,52 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Duration is a time.Duration that can be decoded from strings such as "30s"
+// or "1m30s" in both YAML and JSON configuration files.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	var text string
+	if err := value.Decode(&text); err != nil {
+		return err
+	}
+
+	return d.parse(text)
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+
+	return d.parse(text)
+}
+
+func (d *Duration) parse(text string) error {
+	if text == "" {
+		*d = 0
+
+		return nil
+	}
+
+	duration, err := time.ParseDuration(text)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %w", text, err)
+	}
+
+	*d = Duration(duration)
+
+	return nil
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
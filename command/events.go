@@ -0,0 +1,65 @@
+package command
+
+import (
+	"bytes"
+	"sync"
+)
+
+const (
+	// EventBuildStarted is sent when a reload cycle starts building.
+	EventBuildStarted EventType = iota
+	// EventBuildFailed is sent when the build, or a hook that aborts the
+	// reload, fails. The event carries the error and the build output, and
+	// whether the previous version keeps running.
+	EventBuildFailed
+	// EventStopped is sent after the run commands are stopped.
+	EventStopped
+	// EventStarted is sent after the run commands are started.
+	EventStarted
+	// EventUnchanged is sent when the build output did not change and the
+	// run commands keep running instead of being restarted.
+	EventUnchanged
+)
+
+type EventType int
+
+// Event describes a change in the reload cycle of the commands.
+type Event struct {
+	Type      EventType
+	Err       error
+	Output    string
+	KeptAlive bool
+}
+
+// Subscribe registers fn to receive the events of the reload cycle. It must
+// be called before Start.
+func (c *Commands) Subscribe(fn func(Event)) {
+	c.subscribers = append(c.subscribers, fn)
+}
+
+func (c *Commands) emit(event Event) {
+	for _, fn := range c.subscribers {
+		fn(event)
+	}
+}
+
+// outputBuffer collects the output of a process. It is safe for concurrent
+// use, since stdout and stderr are copied by different goroutines.
+type outputBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (o *outputBuffer) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.buf.Write(p)
+}
+
+func (o *outputBuffer) String() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.buf.String()
+}
@@ -0,0 +1,20 @@
+//go:build !windows
+
+package command
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// describeExit describes the exit code or the signal that terminated the
+// process, and reports whether it was terminated by SIGKILL.
+func describeExit(state *os.ProcessState) (string, bool) {
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if ok && status.Signaled() {
+		return fmt.Sprintf("killed by signal %s", status.Signal()), status.Signal() == syscall.SIGKILL
+	}
+
+	return fmt.Sprintf("exited with code %d", state.ExitCode()), false
+}
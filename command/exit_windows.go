@@ -0,0 +1,13 @@
+//go:build windows
+
+package command
+
+import (
+	"fmt"
+	"os"
+)
+
+// describeExit describes the exit code of the process.
+func describeExit(state *os.ProcessState) (string, bool) {
+	return fmt.Sprintf("exited with code %d", state.ExitCode()), false
+}
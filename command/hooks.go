@@ -0,0 +1,137 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lasfh/eletrize/environments"
+	"github.com/lasfh/eletrize/output"
+)
+
+const (
+	// FailurePolicyAbort stops the current reload when the hook fails.
+	FailurePolicyAbort FailurePolicy = "abort"
+	// FailurePolicyWarn reports the failure and continues the reload.
+	FailurePolicyWarn FailurePolicy = "warn"
+)
+
+type FailurePolicy string
+
+func (f FailurePolicy) isValid() bool {
+	return f == "" || f == FailurePolicyAbort || f == FailurePolicyWarn
+}
+
+type Hook struct {
+	Command   `json:",inline" yaml:",inline"`
+	OnFailure FailurePolicy `json:"on_failure" yaml:"on_failure"`
+}
+
+func (h *Hook) abortOnFailure() bool {
+	return h.OnFailure != FailurePolicyWarn
+}
+
+// Hooks groups the commands executed around each phase of the reload cycle.
+type Hooks struct {
+	BeforeBuild []Hook `json:"before_build" yaml:"before_build"`
+	AfterBuild  []Hook `json:"after_build" yaml:"after_build"`
+	BeforeRun   []Hook `json:"before_run" yaml:"before_run"`
+	AfterStop   []Hook `json:"after_stop" yaml:"after_stop"`
+	OnExit      []Hook `json:"on_exit" yaml:"on_exit"`
+}
+
+type hookPhase struct {
+	name  string
+	hooks []Hook
+}
+
+func (h *Hooks) phases() []hookPhase {
+	return []hookPhase{
+		{"before_build", h.BeforeBuild},
+		{"after_build", h.AfterBuild},
+		{"before_run", h.BeforeRun},
+		{"after_stop", h.AfterStop},
+		{"on_exit", h.OnExit},
+	}
+}
+
+func (h *Hooks) isValidHooks() error {
+	for _, phase := range h.phases() {
+		for i := range phase.hooks {
+			if err := phase.hooks[i].isValidCommand(); err != nil {
+				return fmt.Errorf("%s[%d]: %w", phase.name, i, err)
+			}
+
+			if !phase.hooks[i].OnFailure.isValid() {
+				return fmt.Errorf("%s[%d]: on_failure: invalid policy %q", phase.name, i, phase.hooks[i].OnFailure)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (h *Hooks) prepareHooks(envs environments.Envs, workdir string) error {
+	for _, phase := range h.phases() {
+		for i := range phase.hooks {
+			if err := phase.hooks[i].prepareCommand(envs, workdir); err != nil {
+				return fmt.Errorf("%s[%d]: %w", phase.name, i, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (h *Hooks) quit() {
+	for _, phase := range h.phases() {
+		for i := range phase.hooks {
+			phase.hooks[i].stopProcess()
+		}
+	}
+}
+
+func (h *Hooks) releaseLimits() {
+	for _, phase := range h.phases() {
+		for i := range phase.hooks {
+			phase.hooks[i].releaseLimits()
+		}
+	}
+}
+
+// runHooks executes the hooks of a phase in order. The first hook that fails
+// with the abort policy interrupts the phase and its error is returned.
+func runHooks(ctx context.Context, label *output.Label, phase string, hooks []Hook) error {
+	for i := range hooks {
+		output.Pushf(label, "%s: %s\n", phase, hooks[i].name())
+
+		startTime := time.Now()
+
+		if err := hooks[i].startProcessContext(ctx); err != nil {
+			if ctx.Err() != nil {
+				output.Pushf(label, "%s CANCELED\n", phase)
+
+				return ctx.Err()
+			}
+
+			if hooks[i].abortOnFailure() {
+				output.Pushf(label, "%s FAILED: %s\n", phase, err)
+
+				return fmt.Errorf("%s hook: %w", phase, err)
+			}
+
+			output.Pushf(label, "%s FAILED (ignored): %s\n", phase, err)
+
+			continue
+		}
+
+		output.Pushf(
+			label,
+			"%s DONE (%fs)\n",
+			phase,
+			time.Since(startTime).Seconds(),
+		)
+	}
+
+	return nil
+}
@@ -9,13 +9,32 @@ import (
 )
 
 func KillProcess(cmd *exec.Cmd) error {
+	if err := InterruptProcess(cmd); err != nil {
+		return err
+	}
+
+	_, _ = cmd.Process.Wait()
+
+	return nil
+}
+
+// killProcessGroup sends SIGKILL to the process group of cmd.
+func killProcessGroup(cmd *exec.Cmd) error {
+	if err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) {
+		return err
+	}
+
+	return nil
+}
+
+// InterruptProcess sends SIGINT to the process group of cmd without waiting
+// for it to exit.
+func InterruptProcess(cmd *exec.Cmd) error {
 	pgid := -cmd.Process.Pid
 
 	if err := syscall.Kill(pgid, syscall.SIGINT); err != nil && !errors.Is(err, syscall.ESRCH) {
 		return err
 	}
 
-	_, _ = cmd.Process.Wait()
-
 	return nil
 }
@@ -0,0 +1,153 @@
+package command
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Limits restricts the resources available to the processes of a command.
+type Limits struct {
+	// Memory is the maximum amount of memory, such as "512M" or "2G".
+	Memory ByteSize `json:"memory" yaml:"memory"`
+	// CPU is the number of CPUs the processes may use, such as 0.5 or 2.
+	CPU       float64 `json:"cpu" yaml:"cpu"`
+	OpenFiles uint64  `json:"open_files" yaml:"open_files"`
+	Processes uint64  `json:"processes" yaml:"processes"`
+}
+
+func (l *Limits) isValidLimits() error {
+	if l.CPU < 0 {
+		return errors.New("limits: cpu cannot be negative")
+	}
+
+	return nil
+}
+
+// needsCgroup reports whether the limits are better enforced by a cgroup,
+// which covers the whole process tree of the command.
+func (l *Limits) needsCgroup() bool {
+	return l.Memory > 0 || l.CPU > 0 || l.Processes > 0
+}
+
+const (
+	ioprioClassBestEffort = 2
+	ioprioClassIdle       = 3
+	ioprioClassShift      = 13
+)
+
+// parseIONice parses the I/O scheduling priority of a command, which is
+// either "idle" or "best-effort" followed by an optional level from 0
+// (highest) to 7 (lowest), as in "best-effort:7". Without a level, the
+// lowest one is used.
+func parseIONice(text string) (int, error) {
+	class, level, found := strings.Cut(text, ":")
+
+	switch class {
+	case "idle":
+		if found {
+			return 0, fmt.Errorf("ionice: idle does not accept a level")
+		}
+
+		return ioprioClassIdle << ioprioClassShift, nil
+	case "best-effort":
+		if !found {
+			return ioprioClassBestEffort<<ioprioClassShift | 7, nil
+		}
+
+		n, err := strconv.Atoi(level)
+		if err != nil || n < 0 || n > 7 {
+			return 0, fmt.Errorf("ionice: invalid best-effort level %q, expected 0 to 7", level)
+		}
+
+		return ioprioClassBestEffort<<ioprioClassShift | n, nil
+	}
+
+	return 0, fmt.Errorf("ionice: invalid class %q, expected idle or best-effort", class)
+}
+
+// withWrapper wraps the command line with the commands that set its rlimits
+// and scheduling priorities.
+func (c *Command) withWrapper(name string, args []string) (string, []string) {
+	if len(c.wrapper) == 0 {
+		return name, args
+	}
+
+	return c.wrapper[0], append(append(slices.Clone(c.wrapper[1:]), name), args...)
+}
+
+// ByteSize is an amount of bytes that can be decoded from strings such as
+// "512M" or "2GiB" in both YAML and JSON configuration files. Units are
+// powers of 1024.
+type ByteSize uint64
+
+func (b *ByteSize) UnmarshalYAML(value *yaml.Node) error {
+	var text string
+	if err := value.Decode(&text); err != nil {
+		return err
+	}
+
+	return b.parse(text)
+}
+
+func (b *ByteSize) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		var n uint64
+		if json.Unmarshal(data, &n) != nil {
+			return err
+		}
+
+		*b = ByteSize(n)
+
+		return nil
+	}
+
+	return b.parse(text)
+}
+
+var byteUnits = map[string]uint64{
+	"":  1,
+	"K": 1 << 10,
+	"M": 1 << 20,
+	"G": 1 << 30,
+	"T": 1 << 40,
+}
+
+func (b *ByteSize) parse(text string) error {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		*b = 0
+
+		return nil
+	}
+
+	digits := strings.IndexFunc(text, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if digits == -1 {
+		digits = len(text)
+	}
+
+	unit := strings.ToUpper(strings.TrimSpace(text[digits:]))
+	unit = strings.TrimSuffix(strings.TrimSuffix(unit, "IB"), "B")
+
+	multiplier, ok := byteUnits[unit]
+	if !ok {
+		return fmt.Errorf("invalid size %q: unknown unit", text)
+	}
+
+	n, err := strconv.ParseFloat(text[:digits], 64)
+	if err != nil {
+		return fmt.Errorf("invalid size %q: %w", text, err)
+	}
+
+	*b = ByteSize(n * float64(multiplier))
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package command
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/lasfh/eletrize/output"
+)
+
+// prepareLimits creates the cgroup of the command when its limits need one
+// and sets the wrapper that applies its rlimits and priorities.
+func (c *Command) prepareLimits() {
+	if c.Limits != nil && c.Limits.needsCgroup() && c.cgroup == nil {
+		c.prepareCgroup()
+	}
+
+	c.wrapper = nil
+
+	c.prepareRlimits()
+	c.preparePriority()
+}
+
+// prepareCgroup creates the cgroup of the command. When cgroups are not
+// available, memory is limited by an rlimit and the other limits are not
+// enforced.
+func (c *Command) prepareCgroup() {
+	cgroup, err := newCgroup(c.name(), c.Limits)
+	if err != nil {
+		output.Pushf(output.LabelEletrize, "CGROUP OF %q NOT CREATED: %s\n", c.name(), err)
+
+		// RLIMIT_NPROC counts every process of the user, not only the
+		// ones of the command, and there is no rlimit for the CPU share.
+		if c.Limits.CPU > 0 {
+			output.Pushf(output.LabelEletrize, "CPU LIMIT OF %q NOT ENFORCED: requires a cgroup\n", c.name())
+		}
+
+		if c.Limits.Processes > 0 {
+			output.Pushf(output.LabelEletrize, "PROCESSES LIMIT OF %q NOT ENFORCED: requires a cgroup\n", c.name())
+		}
+
+		if c.Limits.Memory > 0 {
+			output.Pushf(
+				output.LabelEletrize,
+				"MEMORY LIMIT OF %q APPLIED TO THE ADDRESS SPACE OF EACH PROCESS\n", c.name(),
+			)
+		}
+
+		return
+	}
+
+	c.cgroup = cgroup
+}
+
+// configureLimits places the process in the cgroup of the command as it is
+// created, so all of its descendants are accounted for.
+func (c *Command) configureLimits(cmd *exec.Cmd) {
+	if c.cgroup == nil {
+		return
+	}
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(c.cgroup.Fd())
+}
+
+// prepareRlimits adds to the wrapper of the command a shell that sets its
+// rlimits before executing it, so they apply from its first instruction and
+// are inherited by every process it creates.
+func (c *Command) prepareRlimits() {
+	if c.Limits == nil {
+		return
+	}
+
+	var ulimits []string
+
+	if c.Limits.OpenFiles > 0 {
+		ulimits = append(ulimits, "ulimit -n "+strconv.FormatUint(c.Limits.OpenFiles, 10))
+	}
+
+	// Without a cgroup, memory limits the virtual address space, in KiB.
+	if c.Limits.Memory > 0 && c.cgroup == nil {
+		ulimits = append(ulimits, "ulimit -v "+strconv.FormatUint(max(uint64(c.Limits.Memory)/1024, 1), 10))
+	}
+
+	if len(ulimits) > 0 {
+		script := strings.Join(append(ulimits, `exec "$@"`), " && ")
+		c.wrapper = append(c.wrapper, "sh", "-c", script, "sh")
+	}
+}
+
+// preparePriority adds to the wrapper of the command the commands that apply
+// its scheduling priorities before executing it. Both priorities are per
+// thread on linux, so setting them on the process once it started would miss
+// the threads it already created, such as the runtime threads of a Go
+// program, and the processes they create.
+func (c *Command) preparePriority() {
+	if c.Nice != 0 {
+		c.wrapper = append(c.wrapper, "nice", "-n", strconv.Itoa(c.Nice))
+	}
+
+	if c.IONice == "" {
+		return
+	}
+
+	if _, err := exec.LookPath("ionice"); err != nil {
+		output.Pushf(output.LabelEletrize, "IONICE OF %q IGNORED: %s\n", c.name(), err)
+
+		return
+	}
+
+	// The priority was validated with the command.
+	prio, _ := parseIONice(c.IONice)
+	class := prio >> ioprioClassShift
+
+	c.wrapper = append(c.wrapper, "ionice", "-c", strconv.Itoa(class))
+
+	if class == ioprioClassBestEffort {
+		c.wrapper = append(c.wrapper, "-n", strconv.Itoa(prio&(1<<ioprioClassShift-1)))
+	}
+}
+
+// releaseLimits removes the cgroup of the command. Its processes must have
+// been stopped.
+func (c *Command) releaseLimits() {
+	if c.cgroup == nil {
+		return
+	}
+
+	_ = c.cgroup.Close()
+	_ = os.Remove(c.cgroup.Name())
+
+	c.cgroup = nil
+}
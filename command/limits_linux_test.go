@@ -0,0 +1,79 @@
+package command
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCommand_WithWrapper(t *testing.T) {
+	if _, err := exec.LookPath("ionice"); err != nil {
+		t.Skip("ionice is not available")
+	}
+
+	c := &Command{Nice: 10, IONice: "best-effort:4"}
+	c.preparePriority()
+
+	name, args := c.withWrapper("go", []string{"build", "."})
+	expected := []string{"-n", "10", "ionice", "-c", "2", "-n", "4", "go", "build", "."}
+
+	if name != "nice" || !slices.Equal(args, expected) {
+		t.Errorf("withWrapper() = %s %q, want nice %q", name, args, expected)
+	}
+
+	c = &Command{IONice: "idle"}
+	c.preparePriority()
+
+	if name, args := c.withWrapper("go", nil); name != "ionice" || !slices.Equal(args, []string{"-c", "3", "go"}) {
+		t.Errorf("withWrapper() = %s %q, want ionice [-c 3 go]", name, args)
+	}
+}
+
+func TestCommand_StartWithRlimits(t *testing.T) {
+	dir := t.TempDir()
+
+	// The limit applies to the command itself, before it creates any process.
+	c := &Command{
+		Method:  "sh",
+		Args:    []string{"-c", "ulimit -n > " + filepath.Join(dir, "open_files")},
+		Limits:  &Limits{OpenFiles: 64},
+		tracker: &tracker{},
+	}
+	c.prepareLimits()
+
+	if err := c.startProcess(); err != nil {
+		t.Fatalf("startProcess: %v", err)
+	}
+
+	limit, err := os.ReadFile(filepath.Join(dir, "open_files"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.TrimSpace(string(limit)); got != "64" {
+		t.Errorf("Expected the open files limit to be 64, got %s", got)
+	}
+}
+
+func TestCommand_StartWrappedMissingExecutable(t *testing.T) {
+	tests := []struct {
+		method   string
+		expected string
+	}{
+		{"eletrize-missing-executable", "not found in PATH"},
+		{"./bin/app", "has it been built?"},
+	}
+
+	for _, tt := range tests {
+		c := &Command{Method: tt.method, Nice: 10, Workdir: t.TempDir(), tracker: &tracker{}}
+		c.preparePriority()
+
+		_, err := c.start()
+		if err == nil || !strings.Contains(err.Error(), tt.method) || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("start() of %s = %v, want an error with %q", tt.method, err, tt.expected)
+		}
+	}
+}
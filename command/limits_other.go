@@ -0,0 +1,24 @@
+//go:build !linux
+
+package command
+
+import (
+	"os/exec"
+
+	"github.com/lasfh/eletrize/output"
+)
+
+func (c *Command) prepareLimits() {
+	if c.Limits != nil || c.Nice != 0 || c.IONice != "" {
+		output.Pushf(
+			output.LabelEletrize,
+			"LIMITS AND PRIORITIES OF %q IGNORED: only supported on linux\n", c.name(),
+		)
+	}
+}
+
+func (c *Command) configureLimits(cmd *exec.Cmd) {}
+
+func (c *Command) releaseLimits() {}
+
+func releaseCgroups() {}
@@ -0,0 +1,94 @@
+package command
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go.yaml.in/yaml/v3"
+)
+
+func TestByteSize_Parse(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected ByteSize
+	}{
+		{"", 0},
+		{"1024", 1024},
+		{"512K", 512 << 10},
+		{"512M", 512 << 20},
+		{"512MiB", 512 << 20},
+		{"1.5G", 3 << 29},
+		{"2 gb", 2 << 30},
+	}
+
+	for _, tt := range tests {
+		var got ByteSize
+		if err := got.parse(tt.text); err != nil {
+			t.Errorf("parse(%q) unexpected error: %v", tt.text, err)
+
+			continue
+		}
+
+		if got != tt.expected {
+			t.Errorf("parse(%q) = %d, want %d", tt.text, got, tt.expected)
+		}
+	}
+
+	for _, text := range []string{"12X", "M", "1.2.3G"} {
+		var got ByteSize
+		if err := got.parse(text); err == nil {
+			t.Errorf("parse(%q) expected error, got %d", text, got)
+		}
+	}
+}
+
+func TestLimits_Unmarshal(t *testing.T) {
+	var fromYAML Command
+	if err := yaml.Unmarshal([]byte("limits:\n  memory: 256M\n  cpu: 0.5\n  open_files: 1024\nnice: 10\nionice: idle\n"), &fromYAML); err != nil {
+		t.Fatalf("Failed to unmarshal YAML: %v", err)
+	}
+
+	if fromYAML.Limits == nil || fromYAML.Limits.Memory != 256<<20 || fromYAML.Limits.CPU != 0.5 ||
+		fromYAML.Limits.OpenFiles != 1024 || fromYAML.Nice != 10 || fromYAML.IONice != "idle" {
+		t.Errorf("Unexpected command: %+v", fromYAML)
+	}
+
+	var fromJSON Command
+	if err := json.Unmarshal([]byte(`{"limits": {"memory": 1048576, "processes": 64}}`), &fromJSON); err != nil {
+		t.Fatalf("Failed to unmarshal JSON: %v", err)
+	}
+
+	if fromJSON.Limits == nil || fromJSON.Limits.Memory != 1<<20 || fromJSON.Limits.Processes != 64 {
+		t.Errorf("Unexpected command: %+v", fromJSON)
+	}
+}
+
+func TestParseIONice(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected int
+	}{
+		{"idle", 3 << 13},
+		{"best-effort", 2<<13 | 7},
+		{"best-effort:0", 2 << 13},
+	}
+
+	for _, tt := range tests {
+		got, err := parseIONice(tt.text)
+		if err != nil {
+			t.Errorf("parseIONice(%q) unexpected error: %v", tt.text, err)
+
+			continue
+		}
+
+		if got != tt.expected {
+			t.Errorf("parseIONice(%q) = %d, want %d", tt.text, got, tt.expected)
+		}
+	}
+
+	for _, text := range []string{"realtime", "best-effort:8", "idle:1"} {
+		if _, err := parseIONice(text); err == nil {
+			t.Errorf("parseIONice(%q) expected error", text)
+		}
+	}
+}
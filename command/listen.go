@@ -0,0 +1,81 @@
+//go:build !windows
+
+package command
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type fileListener interface {
+	net.Listener
+	File() (*os.File, error)
+}
+
+// openListeners opens the sockets declared in listen. They are kept open by
+// eletrize across restarts, so connections are queued while a new process
+// starts instead of being refused.
+func (c *Command) openListeners() error {
+	for _, address := range c.Listen {
+		network := "tcp"
+
+		if after, ok := strings.CutPrefix(address, "unix:"); ok {
+			network, address = "unix", after
+		}
+
+		listener, err := net.Listen(network, address)
+		if err != nil {
+			c.closeListeners()
+
+			return fmt.Errorf("listen: %w", err)
+		}
+
+		c.listeners = append(c.listeners, listener)
+
+		file, err := listener.(fileListener).File()
+		if err != nil {
+			c.closeListeners()
+
+			return fmt.Errorf("listen: %w", err)
+		}
+
+		c.listenerFiles = append(c.listenerFiles, file)
+	}
+
+	return nil
+}
+
+func (c *Command) closeListeners() {
+	for i := range c.listenerFiles {
+		_ = c.listenerFiles[i].Close()
+	}
+
+	for i := range c.listeners {
+		_ = c.listeners[i].Close()
+	}
+
+	c.listenerFiles = nil
+	c.listeners = nil
+}
+
+// inheritListeners passes the open sockets to the process as inherited file
+// descriptors and sets LISTEN_FDS. LISTEN_PID must hold the PID of the process
+// itself, which is only known after the fork, so the command is wrapped by a
+// shell that exports it before replacing itself with the command.
+func (c *Command) inheritListeners(name string, args []string) (string, []string, []string) {
+	if len(c.listenerFiles) == 0 {
+		return name, args, nil
+	}
+
+	envs := []string{
+		"LISTEN_FDS=" + strconv.Itoa(len(c.listenerFiles)),
+	}
+
+	return "sh", append(
+		[]string{"-c", `LISTEN_PID=$$; export LISTEN_PID; exec "$0" "$@"`, name},
+		args...,
+	), envs
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package command
+
+import "errors"
+
+func (c *Command) openListeners() error {
+	if len(c.Listen) > 0 {
+		return errors.New("listen: socket handoff is not supported on windows")
+	}
+
+	return nil
+}
+
+func (c *Command) closeListeners() {}
+
+func (c *Command) inheritListeners(name string, args []string) (string, []string, []string) {
+	return name, args, nil
+}
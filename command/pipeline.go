@@ -0,0 +1,169 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+
+	"github.com/lasfh/eletrize/environments"
+	"github.com/lasfh/eletrize/output"
+)
+
+type Step struct {
+	Command         `json:",inline" yaml:",inline"`
+	Timeout         Duration `json:"timeout" yaml:"timeout"`
+	ContinueOnError bool     `json:"continue_on_error" yaml:"continue_on_error"`
+}
+
+func (s *Step) run(ctx context.Context) error {
+	stepCtx := ctx
+
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+
+		stepCtx, cancel = context.WithTimeout(ctx, time.Duration(s.Timeout))
+		defer cancel()
+	}
+
+	err := s.startProcessContext(stepCtx)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if errors.Is(stepCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %s", s.Timeout)
+	}
+
+	return err
+}
+
+// Pipeline is the ordered list of steps that make up a build. It can be
+// declared either as a single command or as a list of commands.
+type Pipeline []Step
+
+func (p *Pipeline) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.SequenceNode {
+		var steps []Step
+		if err := value.Decode(&steps); err != nil {
+			return err
+		}
+
+		*p = steps
+
+		return nil
+	}
+
+	var step Step
+	if err := value.Decode(&step); err != nil {
+		return err
+	}
+
+	*p = Pipeline{step}
+
+	return nil
+}
+
+func (p *Pipeline) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	if len(data) > 0 && data[0] == '[' {
+		var steps []Step
+		if err := json.Unmarshal(data, &steps); err != nil {
+			return err
+		}
+
+		*p = steps
+
+		return nil
+	}
+
+	var step Step
+	if err := json.Unmarshal(data, &step); err != nil {
+		return err
+	}
+
+	*p = Pipeline{step}
+
+	return nil
+}
+
+func (p Pipeline) isValidPipeline() error {
+	for i := range p {
+		if err := p[i].isValidCommand(); err != nil {
+			return fmt.Errorf("[%d]: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+func (p Pipeline) preparePipeline(envs environments.Envs, workdir string) error {
+	for i := range p {
+		if err := p[i].prepareCommand(envs, workdir); err != nil {
+			return fmt.Errorf("[%d]: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+func (p Pipeline) quit() {
+	for i := range p {
+		p[i].stopProcess()
+	}
+}
+
+func (p Pipeline) releaseLimits() {
+	for i := range p {
+		p[i].releaseLimits()
+	}
+}
+
+// run executes the steps in order, reporting the duration of each one when
+// the pipeline has more than one step. It stops at the first failing step
+// unless the step is marked with continue_on_error, or when ctx is canceled.
+// The output of the steps is also copied to capture.
+func (p Pipeline) run(ctx context.Context, label *output.Label, capture io.Writer) error {
+	for i := range p {
+		p[i].capture = capture
+
+		startTime := time.Now()
+
+		err := p[i].run(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		elapsed := time.Since(startTime).Seconds()
+
+		if err != nil && p[i].ContinueOnError {
+			output.Pushf(label, "STEP %d/%d %s: FAILED (ignored, %fs): %s\n", i+1, len(p), p[i].name(), elapsed, err)
+
+			continue
+		}
+
+		if len(p) == 1 {
+			return err
+		}
+
+		if err != nil {
+			output.Pushf(label, "STEP %d/%d %s: FAILED (%fs)\n", i+1, len(p), p[i].name(), elapsed)
+
+			return fmt.Errorf("%s: %w", p[i].name(), err)
+		}
+
+		output.Pushf(label, "STEP %d/%d %s: DONE (%fs)\n", i+1, len(p), p[i].name(), elapsed)
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package command
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+func TestPipeline_UnmarshalYAML(t *testing.T) {
+	type Wrapper struct {
+		Build Pipeline `yaml:"build"`
+	}
+
+	// Test case 1: Single command
+	var w1 Wrapper
+	if err := yaml.Unmarshal([]byte("build:\n  method: go\n  args: [build]\n"), &w1); err != nil {
+		t.Fatalf("Failed to unmarshal single command: %v", err)
+	}
+
+	if len(w1.Build) != 1 || w1.Build[0].Method != "go" || len(w1.Build[0].Args) != 1 {
+		t.Errorf("Unexpected pipeline: %+v", w1.Build)
+	}
+
+	// Test case 2: List of steps
+	yamlSteps := `
+build:
+  - label: templ
+    method: templ
+    args: [generate]
+    timeout: 30s
+    continue_on_error: true
+  - method: go
+    args: [build]
+    envs:
+      CGO_ENABLED: "0"
+`
+	var w2 Wrapper
+	if err := yaml.Unmarshal([]byte(yamlSteps), &w2); err != nil {
+		t.Fatalf("Failed to unmarshal steps: %v", err)
+	}
+
+	if len(w2.Build) != 2 {
+		t.Fatalf("Expected 2 steps, got %d", len(w2.Build))
+	}
+
+	if w2.Build[0].Label != "templ" || time.Duration(w2.Build[0].Timeout) != 30*time.Second || !w2.Build[0].ContinueOnError {
+		t.Errorf("Unexpected first step: %+v", w2.Build[0])
+	}
+
+	if w2.Build[1].Envs["CGO_ENABLED"] != "0" {
+		t.Errorf("Expected envs on second step, got %+v", w2.Build[1].Envs)
+	}
+}
+
+func TestPipeline_UnmarshalJSON(t *testing.T) {
+	type Wrapper struct {
+		Build Pipeline `json:"build"`
+	}
+
+	// Test case 1: Single command
+	var w1 Wrapper
+	if err := json.Unmarshal([]byte(`{"build": {"method": "go", "timeout": "1m"}}`), &w1); err != nil {
+		t.Fatalf("Failed to unmarshal single command: %v", err)
+	}
+
+	if len(w1.Build) != 1 || w1.Build[0].Method != "go" || time.Duration(w1.Build[0].Timeout) != time.Minute {
+		t.Errorf("Unexpected pipeline: %+v", w1.Build)
+	}
+
+	// Test case 2: List of steps
+	var w2 Wrapper
+	if err := json.Unmarshal([]byte(`{"build": [{"label": "sqlc", "method": "sqlc"}, {"method": "go"}]}`), &w2); err != nil {
+		t.Fatalf("Failed to unmarshal steps: %v", err)
+	}
+
+	if len(w2.Build) != 2 || w2.Build[0].Label != "sqlc" || w2.Build[1].Method != "go" {
+		t.Errorf("Unexpected pipeline: %+v", w2.Build)
+	}
+
+	// Test case 3: Invalid duration
+	var w3 Wrapper
+	if err := json.Unmarshal([]byte(`{"build": {"method": "go", "timeout": "soon"}}`), &w3); err == nil {
+		t.Error("Expected error for invalid timeout")
+	}
+}
@@ -1,5 +1,4 @@
 //go:build windows
-// +build windows
 
 package command
 
@@ -14,7 +13,7 @@ func startProcess(name string, arg ...string) *exec.Cmd {
 }
 
 func KillProcess(cmd *exec.Cmd) error {
-	if err := cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+	if err := InterruptProcess(cmd); err != nil {
 		return err
 	}
 
@@ -22,3 +21,15 @@ func KillProcess(cmd *exec.Cmd) error {
 
 	return nil
 }
+
+func killProcessGroup(cmd *exec.Cmd) error {
+	return InterruptProcess(cmd)
+}
+
+func InterruptProcess(cmd *exec.Cmd) error {
+	if err := cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return err
+	}
+
+	return nil
+}
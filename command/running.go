@@ -0,0 +1,186 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lasfh/eletrize/output"
+)
+
+// NewProcess returns the command to run name as eletrize runs its own
+// commands, in a new process group that can be stopped with
+// InterruptProcess.
+func NewProcess(name string, arg ...string) *exec.Cmd {
+	return startProcess(name, arg...)
+}
+
+// stopGracePeriod is how long a stopped process has to exit after being
+// interrupted before its process group is killed.
+var stopGracePeriod = 5 * time.Second
+
+// process is a started instance of a command.
+type process struct {
+	name      string
+	cmd       *exec.Cmd
+	input     io.Writer
+	startedAt time.Time
+	stopping  atomic.Bool
+	done      chan struct{}
+}
+
+func newProcess(name string, cmd *exec.Cmd) *process {
+	return &process{
+		name:      name,
+		cmd:       cmd,
+		startedAt: time.Now(),
+		done:      make(chan struct{}),
+	}
+}
+
+// stop interrupts the process group, recording that the stop was initiated
+// by eletrize, and waits for the process to exit, killing the process group
+// when it ignores the interrupt. Descendants that left the process group are
+// interrupted as well, and killed if they outlive it. When the process is
+// already being stopped, it only waits for it to exit.
+func (p *process) stop() {
+	if !p.stopping.CompareAndSwap(false, true) {
+		<-p.done
+
+		return
+	}
+
+	descendants := descendantsOf(p.cmd.Process.Pid)
+
+	if err := InterruptProcess(p.cmd); err != nil {
+		output.Pushf(output.LabelEletrize, "ERROR MESSAGE WHEN KILLING PROCESS: %s\n", err)
+
+		return
+	}
+
+	interruptDescendants(p.cmd.Process.Pid, descendants)
+
+	select {
+	case <-p.done:
+	case <-time.After(stopGracePeriod):
+		output.Pushf(output.LabelEletrize, "KILLING %q: STILL RUNNING %s AFTER THE INTERRUPT\n", p.name, stopGracePeriod)
+
+		if err := killProcessGroup(p.cmd); err != nil {
+			output.Pushf(output.LabelEletrize, "ERROR MESSAGE WHEN KILLING PROCESS: %s\n", err)
+		}
+
+		<-p.done
+	}
+
+	killDescendants(fmt.Sprintf("LEFTOVER PROCESS(ES) OF %q", p.name), descendants)
+}
+
+// wait waits for the process to exit and describes how it finished. Every
+// started process must be waited for exactly once.
+func (p *process) wait() (exit, error) {
+	err := p.cmd.Wait()
+	forgetCommand(p.cmd)
+	recorder.RemoveProcess(p.cmd.Process.Pid)
+	close(p.done)
+
+	return exit{
+		state:    p.cmd.ProcessState,
+		uptime:   time.Since(p.startedAt),
+		stopping: p.stopping.Load(),
+	}, err
+}
+
+// exit describes how a process finished.
+type exit struct {
+	state    *os.ProcessState
+	uptime   time.Duration
+	stopping bool
+}
+
+func (e exit) String() string {
+	description, killed := "finished", false
+	if e.state != nil {
+		description, killed = describeExit(e.state)
+	}
+
+	description = fmt.Sprintf("%s after %s", description, e.uptime.Round(time.Millisecond))
+
+	switch {
+	case e.stopping:
+		description += " (stopped by eletrize)"
+	case killed:
+		description += " (possible OOM kill)"
+	}
+
+	return description
+}
+
+// tracker keeps the process currently running for a command and the error
+// of the last attempt to run it. A command stopped through the control API
+// is not started again by the reload cycles until it is started through it.
+type tracker struct {
+	mu      sync.Mutex
+	process *process
+	err     error
+	stopped bool
+}
+
+func (t *tracker) set(p *process) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.process = p
+	t.err = nil
+}
+
+// fail records that the command could not be started.
+func (t *tracker) fail(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.process = nil
+	t.err = err
+}
+
+func (t *tracker) get() *process {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.process
+}
+
+// exited forgets p, unless another process has been started in the
+// meantime, recording whether it crashed.
+func (t *tracker) exited(p *process, status exit, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.process != p {
+		return
+	}
+
+	t.process = nil
+
+	if err != nil && !status.stopping {
+		t.err = errors.New(status.String())
+	}
+}
+
+func (t *tracker) setStopped(stopped bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.stopped = stopped
+}
+
+func (t *tracker) isStopped() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.stopped
+}
@@ -0,0 +1,44 @@
+//go:build !windows
+
+package command
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProcess_StopIgnoringInterrupt(t *testing.T) {
+	defer func(period time.Duration) { stopGracePeriod = period }(stopGracePeriod)
+
+	stopGracePeriod = 200 * time.Millisecond
+
+	c := &Command{
+		Method:  "sh",
+		Args:    []string{"-c", "trap '' INT; sleep 30"},
+		tracker: &tracker{},
+	}
+
+	p, err := c.start()
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	go func() { _, _ = c.wait(p) }()
+
+	// Gives the shell time to ignore the interrupt.
+	time.Sleep(100 * time.Millisecond)
+
+	stopped := make(chan struct{})
+
+	go func() {
+		p.stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		_ = killProcessGroup(p.cmd)
+		t.Fatal("Expected a process ignoring the interrupt to be killed")
+	}
+}
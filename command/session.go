@@ -0,0 +1,31 @@
+package command
+
+// Recorder records the processes started by eletrize and the artifacts it
+// creates, so they can be cleaned up if eletrize is killed before doing it
+// itself.
+type Recorder interface {
+	AddProcess(pid int, name string)
+	RemoveProcess(pid int)
+	AddArtifact(path string)
+	RemoveArtifact(path string)
+}
+
+// recorder is where the processes and artifacts are recorded. It is set once,
+// before the commands start.
+var recorder Recorder = noRecorder{}
+
+// RecordSession records the processes and artifacts of the commands with r.
+// It must be called before Start.
+func RecordSession(r Recorder) {
+	recorder = r
+}
+
+type noRecorder struct{}
+
+func (noRecorder) AddProcess(pid int, name string) {}
+
+func (noRecorder) RemoveProcess(pid int) {}
+
+func (noRecorder) AddArtifact(path string) {}
+
+func (noRecorder) RemoveArtifact(path string) {}
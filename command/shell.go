@@ -0,0 +1,95 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrUnterminatedQuote = errors.New("unterminated quote")
+
+// Parse splits a command line into a Command using shell word rules: words
+// are separated by whitespace, single quotes preserve their content literally,
+// double quotes allow escaping with a backslash and a backslash outside of
+// quotes escapes the next character.
+func Parse(line string) (Command, error) {
+	words, err := splitWords(line)
+	if err != nil {
+		return Command{}, err
+	}
+
+	if len(words) == 0 {
+		return Command{}, ErrCommandIsEmpty
+	}
+
+	return Command{
+		Method: words[0],
+		Args:   words[1:],
+	}, nil
+}
+
+func splitWords(line string) ([]string, error) {
+	var (
+		words   []string
+		word    strings.Builder
+		inWord  bool
+		escaped bool
+		quote   rune
+	)
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			if quote == '"' && r != '"' && r != '\\' && r != '$' && r != '`' && r != '\n' {
+				word.WriteRune('\\')
+			}
+
+			if r != '\n' {
+				word.WriteRune(r)
+			}
+
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\\':
+			escaped = true
+			inWord = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("%w: %c", ErrUnterminatedQuote, quote)
+	}
+
+	if escaped {
+		word.WriteRune('\\')
+	}
+
+	if inWord {
+		words = append(words, word.String())
+	}
+
+	return words, nil
+}
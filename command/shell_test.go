@@ -0,0 +1,61 @@
+package command
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected []string
+	}{
+		{"Simple", "go build ./...", []string{"go", "build", "./..."}},
+		{"Extra spaces", "  go   build\t-v ", []string{"go", "build", "-v"}},
+		{"Double quotes", `./app --name "hello world"`, []string{"./app", "--name", "hello world"}},
+		{"Single quotes", `echo 'a "b" $c'`, []string{"echo", `a "b" $c`}},
+		{"Escaped space", `ls my\ dir`, []string{"ls", "my dir"}},
+		{"Escape inside double quotes", `echo "say \"hi\" \n"`, []string{"echo", `say "hi" \n`}},
+		{"Empty quoted argument", `./app "" x`, []string{"./app", "", "x"}},
+		{"Adjacent quotes", `-ldflags="-s -w"`, []string{"-ldflags=-s -w"}},
+		{"Empty", "   ", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitWords(tt.line)
+			if err != nil {
+				t.Fatalf("splitWords(%q) unexpected error: %v", tt.line, err)
+			}
+
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("splitWords(%q) = %q, want %q", tt.line, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSplitWords_UnterminatedQuote(t *testing.T) {
+	for _, line := range []string{`echo "abc`, `echo 'abc`} {
+		if _, err := splitWords(line); !errors.Is(err, ErrUnterminatedQuote) {
+			t.Errorf("splitWords(%q) expected ErrUnterminatedQuote, got %v", line, err)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	cmd, err := Parse(`go build -o "bin/my app"`)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	if cmd.Method != "go" || !slices.Equal(cmd.Args, []string{"build", "-o", "bin/my app"}) {
+		t.Errorf("Unexpected command: %+v", cmd)
+	}
+
+	if _, err := Parse(""); !errors.Is(err, ErrCommandIsEmpty) {
+		t.Errorf("Expected ErrCommandIsEmpty, got %v", err)
+	}
+}
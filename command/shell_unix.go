@@ -0,0 +1,31 @@
+//go:build !windows
+
+package command
+
+import (
+	"os"
+	"strings"
+)
+
+// shellCommand returns the shell used to interpret a command line, preferring
+// the user's $SHELL and falling back to sh.
+func shellCommand(line string) (string, []string) {
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "sh"
+	}
+
+	return shell, []string{"-c", line}
+}
+
+// shellQuote quotes s, unless it is made only of characters that are never
+// special to the shell, so that it is taken as a single word.
+func shellQuote(s string) string {
+	if strings.Trim(s, shellSafe) == "" {
+		return s
+	}
+
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+const shellSafe = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789%+,-./:=@_"
@@ -0,0 +1,61 @@
+//go:build !windows
+
+package command
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCommand_CommandLineQuotesPaths(t *testing.T) {
+	t.Setenv("SHELL", "sh")
+
+	vars := templateVars{
+		"build_id":      {"abc123"},
+		"build_output":  {"/tmp/my app/bin"},
+		"changed_files": {"a b.go", "it's.go"},
+		"changed_file":  {""},
+	}
+
+	tests := []struct {
+		name     string
+		command  Command
+		expected []string
+	}{
+		{
+			"Cmd",
+			Command{Cmd: "go vet ${changed_files} ${changed_file} > ${build_output}.${build_id}"},
+			[]string{"go", "vet", "a b.go", "it's.go", ">", "/tmp/my app/bin.abc123"},
+		},
+		{
+			"Shell",
+			Command{Method: "${build_output}", Args: []string{"${changed_files}", "|", "cat"}, Shell: true},
+			[]string{"/tmp/my app/bin", "a b.go", "it's.go", "|", "cat"},
+		},
+		{
+			"Argv",
+			Command{Method: "${build_output}", Args: []string{"${changed_files}"}},
+			[]string{"/tmp/my app/bin", "a b.go", "it's.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.command.vars = vars
+
+			name, args := tt.command.commandLine()
+
+			words := append([]string{name}, args...)
+			if name == "sh" {
+				var err error
+				if words, err = splitWords(args[1]); err != nil {
+					t.Fatalf("splitWords(%q): %v", args[1], err)
+				}
+			}
+
+			if !slices.Equal(words, tt.expected) {
+				t.Errorf("commandLine() = %q, want %q", words, tt.expected)
+			}
+		})
+	}
+}
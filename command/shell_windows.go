@@ -0,0 +1,20 @@
+//go:build windows
+
+package command
+
+import "strings"
+
+// shellCommand returns the shell used to interpret a command line.
+func shellCommand(line string) (string, []string) {
+	return "cmd", []string{"/C", line}
+}
+
+// shellQuote quotes s when it contains spaces or characters special to cmd,
+// so that it is taken as a single word. Paths cannot contain double quotes.
+func shellQuote(s string) string {
+	if !strings.ContainsAny(s, " \t&|<>()^%!,;=") {
+		return s
+	}
+
+	return `"` + s + `"`
+}
@@ -0,0 +1,99 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// startError explains why the process could not be started, detailing how
+// the executable was looked up in path when it was not found.
+func (c *Command) startError(name, path string, err error) error {
+	switch {
+	case errors.Is(err, exec.ErrNotFound):
+		return fmt.Errorf(
+			"executable %q not found in PATH (%s): %w",
+			name, path, err,
+		)
+	case errors.Is(err, fs.ErrNotExist) && strings.ContainsRune(name, filepath.Separator):
+		return fmt.Errorf(
+			"executable %q not found at %s, has it been built? %w",
+			name, c.resolvePath(name), err,
+		)
+	case errors.Is(err, fs.ErrPermission):
+		return fmt.Errorf(
+			"executable %q at %s is not executable: %w",
+			name, c.resolvePath(name), err,
+		)
+	}
+
+	return fmt.Errorf("failed to start %q: %w", name, err)
+}
+
+// lookPath looks up the executable of the command as it is executed by a
+// wrapper: relative to the working directory of the command when name is a
+// path, and in the PATH of env otherwise.
+func (c *Command) lookPath(name string, env []string) error {
+	if strings.ContainsRune(name, filepath.Separator) {
+		return isExecutable(c.resolvePath(name))
+	}
+
+	for _, dir := range filepath.SplitList(pathOf(env)) {
+		if dir == "" {
+			dir = "."
+		}
+
+		if !filepath.IsAbs(dir) {
+			dir = c.resolvePath(dir)
+		}
+
+		if isExecutable(filepath.Join(dir, name)) == nil {
+			return nil
+		}
+	}
+
+	return &exec.Error{Name: name, Err: exec.ErrNotFound}
+}
+
+func isExecutable(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() || info.Mode().Perm()&0o111 == 0 {
+		return fs.ErrPermission
+	}
+
+	return nil
+}
+
+// pathOf returns the last PATH set in env.
+func pathOf(env []string) string {
+	for i := len(env) - 1; i >= 0; i-- {
+		if path, ok := strings.CutPrefix(env[i], "PATH="); ok {
+			return path
+		}
+	}
+
+	return ""
+}
+
+// resolvePath returns the path where the executable is searched for, relative
+// to the working directory of the command.
+func (c *Command) resolvePath(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+
+	dir := c.Workdir
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+
+	return filepath.Join(dir, name)
+}
@@ -0,0 +1,317 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lasfh/eletrize/output"
+)
+
+// States of the schemas and of their run commands.
+const (
+	StateBuilding = "building"
+	StateRunning  = "running"
+	StateCrashed  = "crashed"
+	StateReady    = "ready"
+	StateStopped  = "stopped"
+)
+
+// recentOutputLines is how many lines of output are kept for the control
+// API.
+const recentOutputLines = 1000
+
+var errStop = errors.New("stop requested")
+
+// Status describes the state of the run commands of a schema.
+type Status struct {
+	State    string          `json:"state"`
+	Paused   bool            `json:"paused"`
+	Restarts int             `json:"restarts"`
+	Commands []CommandStatus `json:"commands"`
+	Output   []string        `json:"output,omitempty"`
+	// OutputEnd is the position of the last line of output, from which the
+	// following lines are requested with Logs.
+	OutputEnd int `json:"output_end"`
+}
+
+// CommandStatus describes the state of a run command. The uptime is in
+// seconds.
+type CommandStatus struct {
+	Name      string     `json:"name"`
+	Kind      string     `json:"kind"`
+	State     string     `json:"state"`
+	PID       int        `json:"pid,omitempty"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	Uptime    float64    `json:"uptime,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// Status returns the state of the run commands with the last lines of their
+// output and of the builds.
+func (c *Commands) Status(lines int) Status {
+	building, restarts := c.cycles.state()
+
+	status := Status{
+		Paused:   c.changes.isPaused(),
+		Restarts: restarts,
+	}
+
+	status.Output, status.OutputEnd = c.recent.last(lines)
+
+	for i := range c.Run {
+		status.Commands = append(status.Commands, c.Run[i].status(building))
+	}
+
+	status.State = schemaState(building, status.Commands)
+
+	return status
+}
+
+// Logs returns the lines of output after the position since, or the last
+// lines when since is zero, with the position of the last line.
+func (c *Commands) Logs(since, lines int) ([]string, int) {
+	if since > 0 {
+		return c.recent.since(since)
+	}
+
+	return c.recent.last(lines)
+}
+
+func schemaState(building bool, commands []CommandStatus) string {
+	hasState := func(state string) bool {
+		return slices.ContainsFunc(commands, func(command CommandStatus) bool {
+			return command.State == state
+		})
+	}
+
+	switch {
+	case building:
+		return StateBuilding
+	case hasState(StateCrashed):
+		return StateCrashed
+	case hasState(StateRunning):
+		return StateRunning
+	case !slices.ContainsFunc(commands, func(command CommandStatus) bool {
+		return command.State != StateStopped
+	}):
+		return StateStopped
+	}
+
+	return StateReady
+}
+
+func (c *Command) status(building bool) CommandStatus {
+	status := CommandStatus{
+		Name: c.name(),
+		Kind: KindService,
+	}
+
+	if c.isTask() {
+		status.Kind = KindTask
+	}
+
+	c.tracker.mu.Lock()
+	defer c.tracker.mu.Unlock()
+
+	switch p := c.tracker.process; {
+	case p != nil:
+		startedAt := p.startedAt
+
+		status.State = StateRunning
+		status.PID = p.cmd.Process.Pid
+		status.StartedAt = &startedAt
+		status.Uptime = float64(time.Since(startedAt).Milliseconds()) / 1000
+	case c.tracker.stopped:
+		status.State = StateStopped
+	case c.tracker.err != nil:
+		status.State = StateCrashed
+		status.Error = c.tracker.err.Error()
+	case building:
+		status.State = StateBuilding
+	default:
+		status.State = StateReady
+	}
+
+	return status
+}
+
+// SetPaused pauses or resumes the reload cycles triggered by file changes.
+func (c *Commands) SetPaused(paused bool) {
+	if c.changes.isPaused() != paused {
+		c.TogglePause()
+	}
+}
+
+// StopCommands stops the named run commands, or all of them when no name is
+// given. They are not started again by the reload cycles until they are
+// started with StartCommands.
+func (c *Commands) StopCommands(names ...string) error {
+	indexes, err := c.findCommands(names)
+	if err != nil {
+		return err
+	}
+
+	if len(names) == 0 {
+		c.cycles.next(errStop)
+	}
+
+	// A cycle that is running could otherwise start the commands again
+	// after they are stopped.
+	c.cycles.running.Lock()
+
+	for _, i := range indexes {
+		c.Run[i].tracker.setStopped(true)
+	}
+
+	c.cycles.running.Unlock()
+
+	go func() {
+		for _, i := range indexes {
+			c.Run[i].stopProcess()
+		}
+	}()
+
+	return nil
+}
+
+// StartCommands starts the named run commands that are not running, or all
+// of them when no name is given.
+func (c *Commands) StartCommands(names ...string) error {
+	return c.restartCommands(names, false)
+}
+
+// RestartCommands restarts the named run commands without building them
+// again, or all of them when no name is given.
+func (c *Commands) RestartCommands(names ...string) error {
+	if len(names) == 0 {
+		for i := range c.Run {
+			c.Run[i].tracker.setStopped(false)
+		}
+
+		c.Restart()
+
+		return nil
+	}
+
+	return c.restartCommands(names, true)
+}
+
+func (c *Commands) restartCommands(names []string, restart bool) error {
+	indexes, err := c.findCommands(names)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		c.cycles.running.Lock()
+		defer c.cycles.running.Unlock()
+
+		if c.cycles.isClosed() {
+			return
+		}
+
+		for _, i := range indexes {
+			c.Run[i].tracker.setStopped(false)
+
+			if restart {
+				c.Run[i].stopProcess()
+			} else if c.Run[i].isRunning() {
+				continue
+			}
+
+			if err := c.Run[i].startOnDemand(c.labelRun); err != nil {
+				output.Alertf(c.labelRun, " %s: FAILED TO START ", c.Run[i].name())
+				output.Pushf(c.labelRun, "%s\n", err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// findCommands returns the indexes of the named run commands, or of all of
+// them when no name is given.
+func (c *Commands) findCommands(names []string) ([]int, error) {
+	var indexes []int
+
+	for i := range c.Run {
+		if len(names) == 0 || slices.Contains(names, c.Run[i].name()) {
+			indexes = append(indexes, i)
+		}
+	}
+
+	for _, name := range names {
+		if !slices.ContainsFunc(c.Run, func(command Command) bool {
+			return command.name() == name
+		}) {
+			return nil, fmt.Errorf("run command not found: %s", name)
+		}
+	}
+
+	return indexes, nil
+}
+
+// recentOutput keeps the last lines written to it. Lines are numbered from
+// 1 in the order they are written, so readers can follow the output.
+type recentOutput struct {
+	mu      sync.Mutex
+	lines   []string
+	partial []byte
+	end     int
+}
+
+func (r *recentOutput) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.partial = append(r.partial, p...)
+
+	for {
+		end := bytes.IndexByte(r.partial, '\n')
+		if end < 0 {
+			break
+		}
+
+		r.lines = append(r.lines, strings.TrimRight(string(r.partial[:end]), "\r"))
+		r.partial = r.partial[end+1:]
+		r.end++
+	}
+
+	if len(r.lines) > 2*recentOutputLines {
+		r.lines = slices.Clone(r.lines[len(r.lines)-recentOutputLines:])
+	}
+
+	return len(p), nil
+}
+
+// last returns the last n lines, at most the number of lines kept, and the
+// number of the last line.
+func (r *recentOutput) last(n int) ([]string, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.tail(min(n, recentOutputLines)), r.end
+}
+
+// since returns the lines after the line numbered n that are still kept,
+// and the number of the last line.
+func (r *recentOutput) since(n int) ([]string, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.tail(min(r.end-n, recentOutputLines)), r.end
+}
+
+func (r *recentOutput) tail(n int) []string {
+	n = min(n, len(r.lines))
+	if n <= 0 {
+		return nil
+	}
+
+	return slices.Clone(r.lines[len(r.lines)-n:])
+}
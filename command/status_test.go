@@ -0,0 +1,108 @@
+package command
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestRecentOutput(t *testing.T) {
+	var recent recentOutput
+
+	_, _ = recent.Write([]byte("first\r\nsec"))
+	_, _ = recent.Write([]byte("ond\nthird"))
+
+	if got, end := recent.last(5); !slices.Equal(got, []string{"first", "second"}) || end != 2 {
+		t.Errorf("last(5) = %q, %d, want complete lines only", got, end)
+	}
+
+	_, _ = recent.Write([]byte("\nfourth\n"))
+
+	if got, end := recent.since(2); !slices.Equal(got, []string{"third", "fourth"}) || end != 4 {
+		t.Errorf("since(2) = %q, %d, want the lines after the second", got, end)
+	}
+
+	if got, _ := recent.since(4); got != nil {
+		t.Errorf("since(4) = %q, want nil", got)
+	}
+
+	for i := range 2 * recentOutputLines {
+		_, _ = fmt.Fprintf(&recent, "line %d\n", i)
+	}
+
+	got, _ := recent.last(2 * recentOutputLines)
+	if len(got) != recentOutputLines {
+		t.Fatalf("Expected %d lines to be kept, got %d", recentOutputLines, len(got))
+	}
+
+	if got, _ := recent.since(0); len(got) != recentOutputLines {
+		t.Errorf("Expected the lines no longer kept to be skipped, got %d lines", len(got))
+	}
+
+	if expected := fmt.Sprintf("line %d", 2*recentOutputLines-1); got[len(got)-1] != expected {
+		t.Errorf("Expected last line %q, got %q", expected, got[len(got)-1])
+	}
+
+	if got, _ := recent.last(0); got != nil {
+		t.Errorf("last(0) = %q, want nil", got)
+	}
+}
+
+func TestSchemaState(t *testing.T) {
+	commands := func(states ...string) []CommandStatus {
+		var statuses []CommandStatus
+		for _, state := range states {
+			statuses = append(statuses, CommandStatus{State: state})
+		}
+
+		return statuses
+	}
+
+	tests := []struct {
+		building bool
+		commands []CommandStatus
+		expected string
+	}{
+		{true, commands(StateRunning), StateBuilding},
+		{false, commands(StateRunning, StateCrashed), StateCrashed},
+		{false, commands(StateRunning, StateReady), StateRunning},
+		{false, commands(StateStopped, StateStopped), StateStopped},
+		{false, commands(StateStopped, StateReady), StateReady},
+	}
+
+	for _, tt := range tests {
+		if got := schemaState(tt.building, tt.commands); got != tt.expected {
+			t.Errorf("schemaState(%v, %v) = %q, want %q", tt.building, tt.commands, got, tt.expected)
+		}
+	}
+}
+
+func TestCommands_StopDuringBuild(t *testing.T) {
+	c := &Commands{
+		Build: Pipeline{{Command: Command{Method: "sh", Args: []string{"-c", "sleep 0.3"}}}},
+		Run:   []Command{{Label: "server", Method: "sleep", Args: []string{"30"}}},
+	}
+
+	startCommands(t, c)
+
+	c.Rebuild()
+
+	waitFor(t, "Expected the rebuild to start", func() bool {
+		building, _ := c.cycles.state()
+
+		return building
+	})
+
+	// The cycle building the project must not start the stopped command.
+	if err := c.StopCommands("server"); err != nil {
+		t.Fatalf("StopCommands: %v", err)
+	}
+
+	waitFor(t, "Expected the server to be stopped", func() bool {
+		return !c.Run[0].isRunning()
+	})
+
+	if building, _ := c.cycles.state(); building {
+		t.Error("Expected StopCommands to wait for the cycle")
+	}
+}
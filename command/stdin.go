@@ -0,0 +1,66 @@
+//go:build !windows
+
+package command
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/creack/pty"
+)
+
+// errStdinUnsupported is set where commands cannot read the terminal input.
+var errStdinUnsupported error
+
+// terminal is the pseudo terminal of a command declared with stdin. The
+// process gets its own session with the terminal as its controlling
+// terminal, so interactive programs get line editing and echo, while
+// eletrize writes the input typed by the user to the other side.
+type terminal struct {
+	pty *os.File
+	tty *os.File
+}
+
+func openTerminal(cmd *exec.Cmd) (*terminal, error) {
+	ptmx, tty, err := pty.Open()
+	if err != nil {
+		return nil, fmt.Errorf("stdin: %w", err)
+	}
+
+	size, err := pty.GetsizeFull(os.Stdin)
+	if err != nil || size.Rows == 0 || size.Cols == 0 {
+		size = &pty.Winsize{Rows: 24, Cols: 80}
+	}
+
+	_ = pty.Setsize(ptmx, size)
+
+	cmd.Stdin = tty
+	cmd.Stdout = tty
+	cmd.Stderr = tty
+
+	// A new session is also a new process group, led by the process.
+	cmd.SysProcAttr.Setpgid = false
+	cmd.SysProcAttr.Setsid = true
+	cmd.SysProcAttr.Setctty = true
+	cmd.SysProcAttr.Ctty = 0
+
+	return &terminal{pty: ptmx, tty: tty}, nil
+}
+
+// started copies the output of the terminal to out until every process
+// using it exits.
+func (t *terminal) started(out io.Writer) {
+	_ = t.tty.Close()
+
+	go func() {
+		_, _ = io.Copy(out, t.pty)
+		_ = t.pty.Close()
+	}()
+}
+
+func (t *terminal) close() {
+	_ = t.tty.Close()
+	_ = t.pty.Close()
+}
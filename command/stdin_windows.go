@@ -0,0 +1,24 @@
+//go:build windows
+
+package command
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+)
+
+var errStdinUnsupported = errors.New("stdin: not supported on windows")
+
+type terminal struct {
+	pty *os.File
+}
+
+func openTerminal(cmd *exec.Cmd) (*terminal, error) {
+	return nil, errStdinUnsupported
+}
+
+func (t *terminal) started(out io.Writer) {}
+
+func (t *terminal) close() {}
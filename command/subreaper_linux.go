@@ -0,0 +1,337 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/lasfh/eletrize/output"
+)
+
+const (
+	descendantsGracePeriod = 2 * time.Second
+	descendantsPollDelay   = 50 * time.Millisecond
+)
+
+// subreaper makes eletrize the child subreaper of the processes it starts,
+// so descendants that escape their process group, by calling setsid or
+// daemonizing, are reparented to eletrize instead of init and can still be
+// found and killed.
+//
+// Reparented processes must be reaped by eletrize. Only the zombies that are
+// not the commands started by eletrize are reaped, since those are waited
+// for by os/exec.
+var subreaper struct {
+	mu      sync.Mutex
+	once    sync.Once
+	enabled bool
+	started map[int]*exec.Cmd
+}
+
+// enableSubreaper turns eletrize into a child subreaper and starts reaping
+// the orphaned descendants.
+func enableSubreaper() {
+	subreaper.once.Do(func() {
+		if err := unix.Prctl(unix.PR_SET_CHILD_SUBREAPER, 1, 0, 0, 0); err != nil {
+			output.Pushf(output.LabelEletrize, "SUBREAPER NOT AVAILABLE: %s\n", err)
+
+			return
+		}
+
+		subreaper.enabled = true
+		subreaper.started = make(map[int]*exec.Cmd)
+
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGCHLD)
+
+		go func() {
+			for range signals {
+				reapOrphans()
+			}
+		}()
+	})
+}
+
+// startCommand starts cmd, registering it as a process waited for by
+// os/exec.
+func startCommand(cmd *exec.Cmd) error {
+	subreaper.mu.Lock()
+	defer subreaper.mu.Unlock()
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	if subreaper.enabled {
+		subreaper.started[cmd.Process.Pid] = cmd
+	}
+
+	return nil
+}
+
+// forgetCommand unregisters cmd after it has been waited for.
+func forgetCommand(cmd *exec.Cmd) {
+	subreaper.mu.Lock()
+	defer subreaper.mu.Unlock()
+
+	if subreaper.started[cmd.Process.Pid] == cmd {
+		delete(subreaper.started, cmd.Process.Pid)
+	}
+}
+
+func reapOrphans() {
+	subreaper.mu.Lock()
+	defer subreaper.mu.Unlock()
+
+	procs, err := readProcs()
+	if err != nil {
+		return
+	}
+
+	self := os.Getpid()
+
+	for _, proc := range procs {
+		if proc.ppid != self || proc.state != 'Z' {
+			continue
+		}
+
+		if _, ok := subreaper.started[proc.pid]; ok {
+			continue
+		}
+
+		var status unix.WaitStatus
+		_, _ = unix.Wait4(proc.pid, &status, unix.WNOHANG, nil)
+	}
+}
+
+// procInfo is the information of a process read from /proc/<pid>/stat. The
+// start time tells apart processes that reuse the same PID.
+type procInfo struct {
+	pid   int
+	ppid  int
+	pgid  int
+	state byte
+	start uint64
+	comm  string
+}
+
+func (p procInfo) String() string {
+	return fmt.Sprintf("%d (%s)", p.pid, p.comm)
+}
+
+func readProcs() ([]procInfo, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+
+	procs := make([]procInfo, 0, len(entries))
+
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		if proc, ok := readProc(pid); ok {
+			procs = append(procs, proc)
+		}
+	}
+
+	return procs, nil
+}
+
+func readProc(pid int) (procInfo, bool) {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return procInfo{}, false
+	}
+
+	// The command name is enclosed in parentheses and may contain spaces or
+	// parentheses itself, so the fields are read after the last one.
+	open, end := bytes.IndexByte(data, '('), bytes.LastIndexByte(data, ')')
+	if open < 0 || end < open {
+		return procInfo{}, false
+	}
+
+	fields := strings.Fields(string(data[end+1:]))
+	if len(fields) < 20 {
+		return procInfo{}, false
+	}
+
+	ppid, _ := strconv.Atoi(fields[1])
+	pgid, _ := strconv.Atoi(fields[2])
+	start, _ := strconv.ParseUint(fields[19], 10, 64)
+
+	return procInfo{
+		pid:   pid,
+		ppid:  ppid,
+		pgid:  pgid,
+		state: fields[0][0],
+		start: start,
+		comm:  string(data[open+1 : end]),
+	}, true
+}
+
+// alive reports whether the process is still running, and not a zombie or
+// another process that reused its PID.
+func (p procInfo) alive() bool {
+	current, ok := readProc(p.pid)
+
+	return ok && current.start == p.start && current.state != 'Z'
+}
+
+// descendantsOf returns every process below pid in the process tree.
+func descendantsOf(pid int) []procInfo {
+	procs, err := readProcs()
+	if err != nil {
+		return nil
+	}
+
+	return descendantsIn(procs, pid)
+}
+
+func descendantsIn(procs []procInfo, pid int) []procInfo {
+	children := make(map[int][]procInfo)
+	for _, proc := range procs {
+		children[proc.ppid] = append(children[proc.ppid], proc)
+	}
+
+	var descendants []procInfo
+
+	queue := []int{pid}
+	for len(queue) > 0 {
+		for _, child := range children[queue[0]] {
+			descendants = append(descendants, child)
+			queue = append(queue, child.pid)
+		}
+
+		queue = queue[1:]
+	}
+
+	return descendants
+}
+
+// interruptDescendants sends SIGINT to the descendants that left the process
+// group of pgid, since the group already received it.
+func interruptDescendants(pgid int, descendants []procInfo) {
+	for _, proc := range descendants {
+		if proc.pgid != pgid {
+			_ = unix.Kill(proc.pid, unix.SIGINT)
+		}
+	}
+}
+
+// killDescendants waits for the descendants of a stopped command to exit and
+// kills the ones still running after a grace period, reporting them under
+// the given description.
+func killDescendants(description string, descendants []procInfo) {
+	deadline := time.Now().Add(descendantsGracePeriod)
+
+	for {
+		descendants = aliveProcs(descendants)
+		if len(descendants) == 0 || time.Now().After(deadline) {
+			break
+		}
+
+		time.Sleep(descendantsPollDelay)
+	}
+
+	if len(descendants) == 0 {
+		return
+	}
+
+	for _, proc := range descendants {
+		_ = unix.Kill(proc.pid, unix.SIGKILL)
+	}
+
+	output.Pushf(
+		output.LabelEletrize,
+		"KILLED %d %s: %s\n",
+		len(descendants), description, joinProcs(descendants),
+	)
+
+	time.Sleep(descendantsPollDelay)
+
+	if survivors := aliveProcs(descendants); len(survivors) > 0 {
+		output.Alertf(
+			output.LabelEletrize,
+			" %d %s SURVIVED: %s ",
+			len(survivors), description, joinProcs(survivors),
+		)
+	}
+}
+
+// killOrphans kills the descendants of eletrize that no longer belong to a
+// running command, such as daemons whose parent has exited.
+func killOrphans() {
+	if !subreaper.enabled {
+		return
+	}
+
+	var orphans []procInfo
+
+	subreaper.mu.Lock()
+
+	procs, err := readProcs()
+	if err != nil {
+		subreaper.mu.Unlock()
+
+		return
+	}
+
+	for _, proc := range procs {
+		if proc.ppid != os.Getpid() || proc.state == 'Z' {
+			continue
+		}
+
+		if _, ok := subreaper.started[proc.pid]; ok {
+			continue
+		}
+
+		orphans = append(orphans, proc)
+		orphans = append(orphans, descendantsIn(procs, proc.pid)...)
+	}
+
+	subreaper.mu.Unlock()
+
+	if len(orphans) == 0 {
+		return
+	}
+
+	for _, proc := range orphans {
+		_ = unix.Kill(proc.pid, unix.SIGINT)
+	}
+
+	killDescendants("ORPHANED PROCESS(ES)", orphans)
+}
+
+func aliveProcs(procs []procInfo) []procInfo {
+	var alive []procInfo
+
+	for _, proc := range procs {
+		if proc.alive() {
+			alive = append(alive, proc)
+		}
+	}
+
+	return alive
+}
+
+func joinProcs(procs []procInfo) string {
+	names := make([]string, len(procs))
+	for i, proc := range procs {
+		names[i] = proc.String()
+	}
+
+	return strings.Join(names, ", ")
+}
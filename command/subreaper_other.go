@@ -0,0 +1,27 @@
+//go:build !linux
+
+package command
+
+import "os/exec"
+
+// procInfo is only tracked on linux, where eletrize is the subreaper of the
+// processes it starts.
+type procInfo struct{}
+
+func enableSubreaper() {}
+
+func startCommand(cmd *exec.Cmd) error {
+	return cmd.Start()
+}
+
+func forgetCommand(cmd *exec.Cmd) {}
+
+func descendantsOf(pid int) []procInfo {
+	return nil
+}
+
+func interruptDescendants(pgid int, descendants []procInfo) {}
+
+func killDescendants(description string, descendants []procInfo) {}
+
+func killOrphans() {}
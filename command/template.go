@@ -0,0 +1,230 @@
+package command
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var templateVarRegex = regexp.MustCompile(`\$\{([a-z_]+)\}`)
+
+// templateVars holds the values of the ${...} variables available to the
+// method, args, cmd and envs of the commands during a reload cycle. Lists,
+// such as the changed files, are joined with spaces, except in args that
+// consist only of the variable, which are expanded into one argument per
+// value. Unknown variables are left untouched, so they can still be expanded
+// by the shell.
+type templateVars map[string][]string
+
+// pathVars are the variables holding paths, which may contain spaces or
+// other characters special to the shell.
+var pathVars = []string{"build_output", "changed_file", "changed_files", "changed_pkg"}
+
+func (v templateVars) expand(text string) string {
+	return v.expandQuoting(text, false)
+}
+
+// expandShell expands text like expand, but quotes the values of the path
+// variables, so the shell interpreting text takes each one as a single word.
+func (v templateVars) expandShell(text string) string {
+	return v.expandQuoting(text, true)
+}
+
+func (v templateVars) expandQuoting(text string, quote bool) string {
+	if v == nil || !strings.Contains(text, "${") {
+		return text
+	}
+
+	return templateVarRegex.ReplaceAllStringFunc(text, func(match string) string {
+		name := match[2 : len(match)-1]
+
+		values, ok := v[name]
+		if !ok {
+			return match
+		}
+
+		if quote && slices.Contains(pathVars, name) {
+			quoted := make([]string, 0, len(values))
+			for _, value := range values {
+				if value != "" {
+					quoted = append(quoted, shellQuote(value))
+				}
+			}
+
+			values = quoted
+		}
+
+		return strings.Join(values, " ")
+	})
+}
+
+func (v templateVars) expandArgs(args []string) []string {
+	if v == nil {
+		return args
+	}
+
+	expanded := make([]string, 0, len(args))
+
+	for _, arg := range args {
+		if match := templateVarRegex.FindStringSubmatch(arg); match != nil && match[0] == arg {
+			if values, ok := v[match[1]]; ok {
+				expanded = append(expanded, values...)
+
+				continue
+			}
+		}
+
+		expanded = append(expanded, v.expand(arg))
+	}
+
+	return expanded
+}
+
+// changes collects the files changed since the last reload cycle.
+type changes struct {
+	mu     sync.Mutex
+	files  []string
+	paused bool
+}
+
+func (c *changes) add(names ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, name := range names {
+		if !slices.Contains(c.files, name) {
+			c.files = append(c.files, name)
+		}
+	}
+}
+
+// take returns the changed files and starts collecting them again.
+func (c *changes) take() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	files := c.files
+	c.files = nil
+
+	return files
+}
+
+// pending reports whether files changed since the last reload cycle.
+func (c *changes) pending() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.files) > 0
+}
+
+func (c *changes) isPaused() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.paused
+}
+
+// togglePause pauses or resumes the reload cycles, reporting whether they
+// are paused and whether files changed in the meantime.
+func (c *changes) togglePause() (paused, changed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.paused = !c.paused
+
+	return c.paused, len(c.files) > 0
+}
+
+// cycleVars returns the template variables of a new reload cycle.
+func (c *Commands) cycleVars(files []string) templateVars {
+	_, restarts := c.cycles.state()
+
+	vars := templateVars{
+		"label":         {c.label},
+		"workdir":       {c.workdir},
+		"build_id":      {newBuildID()},
+		"restart_count": {strconv.Itoa(restarts)},
+		"changed_files": files,
+		"changed_file":  {""},
+		"changed_pkg":   changedPackages(files),
+	}
+
+	if len(files) > 0 {
+		vars["changed_file"] = files[:1]
+	}
+
+	if port, err := freePort(); err == nil {
+		vars["free_port"] = []string{strconv.Itoa(port)}
+	}
+
+	if c.Output != "" {
+		output := vars.expand(c.Output)
+		if !filepath.IsAbs(output) {
+			output = filepath.Join(c.workdir, output)
+		}
+
+		vars["build_output"] = []string{output}
+	}
+
+	return vars
+}
+
+// relativeFile returns the path of a changed file relative to workdir.
+func relativeFile(workdir, name string) string {
+	if !filepath.IsAbs(name) {
+		return filepath.Clean(name)
+	}
+
+	if rel, err := filepath.Rel(workdir, name); err == nil {
+		return rel
+	}
+
+	return name
+}
+
+// changedPackages returns the directories of the changed files in the form
+// of Go package patterns, such as "./internal/server".
+func changedPackages(files []string) []string {
+	var packages []string
+
+	for _, file := range files {
+		dir := filepath.Dir(file)
+		if !filepath.IsAbs(dir) {
+			dir = "./" + filepath.ToSlash(dir)
+			if dir == "./." {
+				dir = "."
+			}
+		}
+
+		if !slices.Contains(packages, dir) {
+			packages = append(packages, dir)
+		}
+	}
+
+	return packages
+}
+
+func newBuildID() string {
+	id := make([]byte, 4)
+	_, _ = rand.Read(id)
+
+	return hex.EncodeToString(id)
+}
+
+// freePort returns a TCP port that is free at the moment it is called.
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, err
+	}
+
+	defer listener.Close()
+
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
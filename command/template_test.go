@@ -0,0 +1,50 @@
+package command
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTemplateVars_Expand(t *testing.T) {
+	vars := templateVars{
+		"build_id":      {"abc123"},
+		"changed_files": {"a.go", "pkg/b.go"},
+		"changed_file":  {"a.go"},
+	}
+
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"bin/app-${build_id}", "bin/app-abc123"},
+		{"files: ${changed_files}", "files: a.go pkg/b.go"},
+		{"${changed_file}:${build_id}", "a.go:abc123"},
+		{"$HOME ${HOME} ${unknown}", "$HOME ${HOME} ${unknown}"},
+	}
+
+	for _, tt := range tests {
+		if got := vars.expand(tt.text); got != tt.expected {
+			t.Errorf("expand(%q) = %q, want %q", tt.text, got, tt.expected)
+		}
+	}
+
+	args := vars.expandArgs([]string{"test", "-run", ".", "${changed_files}", "-o=${build_id}"})
+	expected := []string{"test", "-run", ".", "a.go", "pkg/b.go", "-o=abc123"}
+
+	if !slices.Equal(args, expected) {
+		t.Errorf("expandArgs = %q, want %q", args, expected)
+	}
+
+	if args := (templateVars{"changed_files": nil}).expandArgs([]string{"vet", "${changed_files}"}); !slices.Equal(args, []string{"vet"}) {
+		t.Errorf("Expected empty list to expand to no arguments, got %q", args)
+	}
+}
+
+func TestChangedPackages(t *testing.T) {
+	got := changedPackages([]string{"main.go", "internal/server/http.go", "internal/server/routes.go", "web/app.js"})
+	expected := []string{".", "./internal/server", "./web"}
+
+	if !slices.Equal(got, expected) {
+		t.Errorf("changedPackages = %q, want %q", got, expected)
+	}
+}
@@ -1,12 +1,18 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
 	"runtime"
 	"runtime/debug"
 	"strings"
+	"syscall"
 
 	"github.com/lasfh/eletrize/command"
+	"github.com/lasfh/eletrize/control"
+	"github.com/lasfh/eletrize/gotest"
 	"github.com/lasfh/eletrize/output"
 	"github.com/lasfh/eletrize/schema"
 	"github.com/lasfh/eletrize/watcher"
@@ -27,14 +33,20 @@ func execute() error {
 		Args:          cobra.MaximumNArgs(1),
 		SilenceUsage:  true,
 		SilenceErrors: true,
-		PreRunE: func(cmd *cobra.Command, args []string) error {
-			return lock()
-		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			var (
-				eletrize *Eletrize
-				err      error
-			)
+			var config string
+			if len(args) > 0 {
+				config = args[0]
+			}
+
+			unlock, err := lock(config)
+			if err != nil {
+				return err
+			}
+
+			defer unlock()
+
+			var eletrize *Eletrize
 
 			if len(args) == 0 {
 				eletrize, err = NewEletrizeFromWD()
@@ -71,12 +83,63 @@ func execute() error {
 	rootCmd.Flags().UintSliceVarP(&schema, "schema", "s", []uint{}, "Execute a specific schema")
 	rootCmd.AddCommand(
 		runCommand(),
+		testCommand(),
 		versionCommand(),
+		statusCommand(),
+		actionCommand(control.ActionRestart, "Restart the run commands of the running eletrize", true),
+		actionCommand(control.ActionStop, "Stop the run commands of the running eletrize", true),
+		actionCommand(control.ActionStart, "Start the run commands stopped in the running eletrize", true),
+		actionCommand(control.ActionRebuild, "Rebuild the running eletrize", false),
+		logsCommand(),
 	)
 
 	return rootCmd.Execute()
 }
 
+func testCommand() *cobra.Command {
+	var path string
+
+	cmd := &cobra.Command{
+		Use:   "test [-- go test flags]",
+		Short: "Run the tests of the Go packages affected by each change",
+		Long: `The “test” command runs the tests of the whole module once, then, on every change, only the tests of the packages affected by the changed files, including the packages that depend on them.
+		Press f to rerun only the failing tests, a to test every package again, c to clear the screen or q to quit.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+
+			runner := gotest.NewRunner(gotest.Options{
+				Path: path,
+				Args: args,
+			}, nil)
+
+			restore := testKeyboard(runner, cancel).listen()
+			defer restore()
+
+			return runner.Run(ctx)
+		},
+	}
+
+	cmd.Flags().StringVarP(&path, "path", "p", ".", "Set the path to watch for changes")
+
+	return cmd
+}
+
+// testKeyboard returns the keyboard controls of the test watch mode.
+func testKeyboard(runner *gotest.Runner, quit func()) *keyboard {
+	return &keyboard{
+		commands: map[byte]func(){
+			'f': runner.RerunFailed,
+			'a': runner.RunAll,
+			'c': clearTerminal,
+			'q': quit,
+		},
+	}
+}
+
 func runCommand() *cobra.Command {
 	var (
 		label      string
@@ -85,6 +148,7 @@ func runCommand() *cobra.Command {
 		extensions []string
 		envFile    string
 		workdir    string
+		shell      bool
 	)
 
 	cmd := &cobra.Command{
@@ -94,34 +158,29 @@ func runCommand() *cobra.Command {
 		You can include optional [run] and [build] arguments to customize the build process and specify the command to run.`,
 		Args: cobra.RangeArgs(1, 2),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			var (
-				build *command.Command
-				run   command.Command
-			)
+			unlock, err := lock("")
+			if err != nil {
+				return err
+			}
+
+			defer unlock()
+
+			var build command.Pipeline
 
 			if len(args) == 2 {
 				// build command
-				parts := strings.Fields(args[1])
-
-				build = &command.Command{
-					Method: strings.TrimSpace(
-						parts[0],
-					),
-					Args: []string{},
+				step, err := parseCommandLine(args[1], shell)
+				if err != nil {
+					return fmt.Errorf("build: %w", err)
 				}
 
-				if len(parts) > 1 {
-					build.Args = parts[1:]
-				}
+				build = command.Pipeline{{Command: step}}
 			}
 
 			// run command
-			parts := strings.Fields(args[0])
-
-			run.Method = parts[0]
-
-			if len(parts) > 1 {
-				run.Args = parts[1:]
+			run, err := parseCommandLine(args[0], shell)
+			if err != nil {
+				return fmt.Errorf("run: %w", err)
 			}
 
 			eletrize := &Eletrize{
@@ -155,10 +214,25 @@ func runCommand() *cobra.Command {
 	cmd.PersistentFlags().StringSliceVarP(&extensions, "ext", "e", []string{}, "Set file extensions to watch")
 	cmd.PersistentFlags().StringVarP(&envFile, "env", "", "", "Set the path to the environment file")
 	cmd.PersistentFlags().StringVarP(&workdir, "workdir", "", "", "Sets the working directory")
+	cmd.PersistentFlags().BoolVarP(&shell, "shell", "", false, "Run the commands through the shell")
 
 	return cmd
 }
 
+// parseCommandLine converts a command line received as an argument into a
+// command, either interpreted by the shell or split into shell words.
+func parseCommandLine(line string, shell bool) (command.Command, error) {
+	if shell {
+		if strings.TrimSpace(line) == "" {
+			return command.Command{}, command.ErrCommandIsEmpty
+		}
+
+		return command.Command{Cmd: line}, nil
+	}
+
+	return command.Parse(line)
+}
+
 func versionCommand() *cobra.Command {
 	var debugInfo bool
 
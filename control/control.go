@@ -0,0 +1,97 @@
+// Package control implements the control API of a running eletrize: a JSON
+// API served on a Unix domain socket per workspace, used by editor plugins
+// and scripts to inspect and drive the schemas.
+package control
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/lasfh/eletrize/command"
+	"github.com/lasfh/eletrize/workspace"
+)
+
+// Actions of the control API.
+const (
+	ActionStatus  = "status"
+	ActionRestart = "restart"
+	ActionRebuild = "rebuild"
+	ActionStop    = "stop"
+	ActionStart   = "start"
+	ActionPause   = "pause"
+	ActionResume  = "resume"
+	ActionLogs    = "logs"
+)
+
+// DefaultOutputLines is how many lines of output a status returns when the
+// request does not set it.
+const DefaultOutputLines = 50
+
+const requestTimeout = 10 * time.Second
+
+// Request is a request to the control API. Schema is the number of the
+// schema, starting from 1, and Command the label or command line of one of
+// its run commands; when they are not set, the action applies to every
+// schema or to every run command of the schema. Logs requests return the
+// lines of output after the position Since, or the last Lines lines.
+type Request struct {
+	Action  string `json:"action"`
+	Schema  int    `json:"schema,omitempty"`
+	Command string `json:"command,omitempty"`
+	Lines   int    `json:"lines,omitempty"`
+	Since   int    `json:"since,omitempty"`
+}
+
+// Response is the response of the control API. Status requests are answered
+// with the state of the schemas, logs requests with their output only, and
+// other actions only with their error.
+type Response struct {
+	Error   string         `json:"error,omitempty"`
+	Schemas []SchemaStatus `json:"schemas,omitempty"`
+}
+
+// SchemaStatus describes the state of a schema and of its run commands.
+type SchemaStatus struct {
+	Number int    `json:"number"`
+	Label  string `json:"label"`
+	Error  string `json:"error,omitempty"`
+	command.Status
+}
+
+// SocketPath returns the path of the socket of the control API of the
+// workspace in dir.
+func SocketPath(dir string) string {
+	return workspace.RuntimeFile(dir, ".sock")
+}
+
+// SchemaSocketPath returns the path of the socket of a schema running in its
+// own process, when several schemas of the workspace in dir run at once.
+func SchemaSocketPath(dir string, schema int) string {
+	return workspace.RuntimeFile(dir, fmt.Sprintf("-%d.sock", schema))
+}
+
+// Send sends request to the control API listening on path and returns its
+// response.
+func Send(path string, request Request) (Response, error) {
+	conn, err := net.DialTimeout("unix", path, requestTimeout)
+	if err != nil {
+		return Response{}, err
+	}
+
+	defer conn.Close()
+
+	_ = conn.SetDeadline(time.Now().Add(requestTimeout))
+
+	if err := json.NewEncoder(conn).Encode(request); err != nil {
+		return Response{}, err
+	}
+
+	var response Response
+	if err := json.NewDecoder(conn).Decode(&response); err != nil {
+		return Response{}, err
+	}
+
+	return response, nil
+}
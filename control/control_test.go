@@ -0,0 +1,108 @@
+package control
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSocketPath(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+
+	path := SocketPath("/home/user/project")
+	if !strings.HasPrefix(path, "/run/user/1000/eletrize-") || !strings.HasSuffix(path, ".sock") {
+		t.Errorf("Unexpected socket path: %s", path)
+	}
+
+	if path == SocketPath("/home/user/other") {
+		t.Error("Expected workspaces to have different sockets")
+	}
+
+	if path == SchemaSocketPath("/home/user/project", 1) {
+		t.Error("Expected schemas to have their own sockets")
+	}
+}
+
+func TestServer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "control.sock")
+
+	// A socket left behind by an instance that is no longer running.
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := Listen(path, func(request Request) Response {
+		if request.Action != ActionStatus {
+			return Response{Error: "unexpected action: " + request.Action}
+		}
+
+		return Response{Schemas: []SchemaStatus{{Number: request.Schema, Label: "api"}}}
+	})
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+
+	defer server.Close()
+
+	response, err := Send(path, Request{Action: ActionStatus, Schema: 2})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if len(response.Schemas) != 1 || response.Schemas[0].Number != 2 || response.Schemas[0].Label != "api" {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+
+	if _, err := Listen(path, nil); err == nil {
+		t.Error("Expected a socket in use to be refused")
+	}
+}
+
+func TestForwardHandler(t *testing.T) {
+	dir := t.TempDir()
+	sockets := map[int]string{
+		1: filepath.Join(dir, "1.sock"),
+		3: filepath.Join(dir, "3.sock"),
+	}
+
+	server, err := Listen(sockets[3], func(request Request) Response {
+		if request.Action != ActionStatus {
+			return Response{}
+		}
+
+		return Response{Schemas: []SchemaStatus{{Number: request.Schema, Label: "web"}}}
+	})
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+
+	defer server.Close()
+
+	handler := ForwardHandler(sockets, map[int]string{1: "api", 3: "web"})
+
+	response := handler(Request{Action: ActionStatus})
+	if len(response.Schemas) != 2 {
+		t.Fatalf("Expected the status of 2 schemas, got %+v", response)
+	}
+
+	if response.Schemas[0].Number != 1 || response.Schemas[0].Error == "" {
+		t.Errorf("Expected schema 1 to report that it is not reachable, got %+v", response.Schemas[0])
+	}
+
+	if response.Schemas[1].Label != "web" || response.Schemas[1].Error != "" {
+		t.Errorf("Unexpected status of schema 3: %+v", response.Schemas[1])
+	}
+
+	if response := handler(Request{Action: ActionRestart, Schema: 3}); response.Error != "" {
+		t.Errorf("Unexpected error: %s", response.Error)
+	}
+
+	if response := handler(Request{Action: ActionRestart}); !strings.Contains(response.Error, "schema 1") {
+		t.Errorf("Expected the error of schema 1, got %q", response.Error)
+	}
+
+	if response := handler(Request{Action: ActionRestart, Schema: 2}); response.Error == "" {
+		t.Error("Expected an unknown schema to fail")
+	}
+}
@@ -0,0 +1,148 @@
+package control
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/lasfh/eletrize/command"
+)
+
+// SchemaHandler answers the requests for a schema running in this process.
+// Requests are refused until started reports that its commands have started.
+func SchemaHandler(number int, label string, commands *command.Commands, started func() bool) Handler {
+	return func(request Request) Response {
+		if request.Schema != 0 && request.Schema != number {
+			return errorResponse(fmt.Errorf("schema not found: %d", request.Schema))
+		}
+
+		if !started() {
+			return errorResponse(errors.New("schema is starting"))
+		}
+
+		lines := request.Lines
+		if lines == 0 {
+			lines = DefaultOutputLines
+		}
+
+		switch request.Action {
+		case ActionStatus:
+			return Response{
+				Schemas: []SchemaStatus{{
+					Number: number,
+					Label:  label,
+					Status: commands.Status(lines),
+				}},
+			}
+		case ActionLogs:
+			status := SchemaStatus{Number: number, Label: label}
+			status.Output, status.OutputEnd = commands.Logs(request.Since, lines)
+
+			return Response{Schemas: []SchemaStatus{status}}
+		}
+
+		return errorResponse(runAction(commands, request))
+	}
+}
+
+func runAction(commands *command.Commands, request Request) error {
+	var names []string
+	if request.Command != "" {
+		names = []string{request.Command}
+	}
+
+	switch request.Action {
+	case ActionRestart:
+		return commands.RestartCommands(names...)
+	case ActionStop:
+		return commands.StopCommands(names...)
+	case ActionStart:
+		return commands.StartCommands(names...)
+	case ActionRebuild, ActionPause, ActionResume:
+		if request.Command != "" {
+			return fmt.Errorf("%s applies to a whole schema", request.Action)
+		}
+	default:
+		return fmt.Errorf("unknown action: %q", request.Action)
+	}
+
+	switch request.Action {
+	case ActionRebuild:
+		commands.Rebuild()
+	case ActionPause:
+		commands.SetPaused(true)
+	case ActionResume:
+		commands.SetPaused(false)
+	}
+
+	return nil
+}
+
+// ForwardHandler answers the requests for several schemas, each one running
+// in its own process and serving the control API on its own socket. Status
+// and logs requests are answered for every schema requested.
+func ForwardHandler(sockets map[int]string, labels map[int]string) Handler {
+	return func(request Request) Response {
+		if request.Schema != 0 {
+			path, ok := sockets[request.Schema]
+			if !ok {
+				return errorResponse(fmt.Errorf("schema not found: %d", request.Schema))
+			}
+
+			response, err := Send(path, request)
+			if err != nil {
+				return errorResponse(fmt.Errorf("schema %d: %w", request.Schema, err))
+			}
+
+			return response
+		}
+
+		var (
+			response Response
+			errs     []error
+		)
+
+		for _, number := range slices.Sorted(maps.Keys(sockets)) {
+			path := sockets[number]
+			request.Schema = number
+
+			forwarded, err := Send(path, request)
+			if err == nil && forwarded.Error != "" {
+				err = errors.New(forwarded.Error)
+			}
+
+			if err != nil {
+				errs = append(errs, fmt.Errorf("schema %d: %w", number, err))
+
+				if request.Action == ActionStatus || request.Action == ActionLogs {
+					response.Schemas = append(response.Schemas, SchemaStatus{
+						Number: number,
+						Label:  labels[number],
+						Error:  err.Error(),
+					})
+				}
+
+				continue
+			}
+
+			response.Schemas = append(response.Schemas, forwarded.Schemas...)
+		}
+
+		if request.Action != ActionStatus && request.Action != ActionLogs {
+			if err := errors.Join(errs...); err != nil {
+				response.Error = err.Error()
+			}
+		}
+
+		return response
+	}
+}
+
+func errorResponse(err error) Response {
+	if err == nil {
+		return Response{}
+	}
+
+	return Response{Error: err.Error()}
+}
@@ -0,0 +1,99 @@
+package control
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// Handler answers the requests of the control API.
+type Handler func(request Request) Response
+
+// Server serves the control API on a Unix domain socket.
+type Server struct {
+	listener net.Listener
+	handler  Handler
+}
+
+// Listen serves the control API on the socket at path. A socket left behind
+// by an instance that is no longer running is replaced.
+func Listen(path string, handler Handler) (*Server, error) {
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("control: %w", err)
+	}
+
+	_ = os.Chmod(path, 0o600)
+
+	s := &Server{
+		listener: listener,
+		handler:  handler,
+	}
+
+	go s.serve()
+
+	return s, nil
+}
+
+// Close stops serving the control API and removes the socket.
+func (s *Server) Close() error {
+	return s.listener.Close()
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	_ = conn.SetDeadline(time.Now().Add(requestTimeout))
+
+	var (
+		request  Request
+		response Response
+	)
+
+	if err := json.NewDecoder(conn).Decode(&request); err != nil {
+		response.Error = fmt.Sprintf("invalid request: %s", err)
+	} else {
+		response = s.handler(request)
+	}
+
+	_ = json.NewEncoder(conn).Encode(response)
+}
+
+// removeStaleSocket removes the socket at path unless an instance is still
+// listening on it.
+func removeStaleSocket(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err == nil {
+		_ = conn.Close()
+
+		return fmt.Errorf("control: %s is in use by another instance", path)
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("control: %w", err)
+	}
+
+	return nil
+}
@@ -67,9 +67,13 @@ func runGoProject(path string) (*Eletrize, error) {
 					ExcludedPaths: []string{"vendor"},
 				},
 				Commands: command.Commands{
-					Build: &command.Command{
-						Method: "go",
-						Args:   []string{"build", "-gcflags=all=-N -l"},
+					Build: command.Pipeline{
+						{
+							Command: command.Command{
+								Method: "go",
+								Args:   []string{"build", "-gcflags=all=-N -l"},
+							},
+						},
 					},
 					Run: []command.Command{
 						{
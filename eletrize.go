@@ -21,9 +21,11 @@ import (
 	"go.yaml.in/yaml/v3"
 
 	"github.com/lasfh/eletrize/command"
+	"github.com/lasfh/eletrize/control"
 	"github.com/lasfh/eletrize/output"
 	"github.com/lasfh/eletrize/schema"
 	"github.com/lasfh/eletrize/vscode"
+	"github.com/lasfh/eletrize/workspace"
 )
 
 type Eletrize struct {
@@ -165,14 +167,51 @@ func (e *Eletrize) Start(args []string, onlySchema ...uint) error {
 		cancel()
 	}()
 
-	if err := e.Schema[index].Start(ctx); err != nil {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	s := &e.Schema[index]
+	started := commandsStarted(&s.Commands)
+
+	session, err := workspace.StartSession(wd, int(index)+1)
+	if err != nil {
+		return err
+	}
+
+	defer session.End()
+
+	command.RecordSession(session)
+
+	socket := control.SocketPath(wd)
+	if os.Getenv("ELETRIZE_SUB") == "1" {
+		socket = control.SchemaSocketPath(wd, int(index)+1)
+	}
+
+	closeControl := serveControl(socket, control.SchemaHandler(int(index)+1, schemaLabel(s), &s.Commands, started))
+	defer closeControl()
+
+	k := schemaKeyboard(s, started, signalChan)
+
+	// The subprocesses of several schemas receive the commands of the
+	// control signals through their keyboard, and keep the default action
+	// of SIGHUP, sent when their terminal is closed.
+	if os.Getenv("ELETRIZE_SUB") != "1" {
+		k.handleSignals()
+	}
+
+	restore := k.listen()
+	defer restore()
+
+	if err := s.Start(ctx); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (e *Eletrize) startMany(signalChan <-chan os.Signal, args []string, onlySchema ...uint) error {
+func (e *Eletrize) startMany(signalChan chan os.Signal, args []string, onlySchema ...uint) error {
 	if err := os.Setenv("ELETRIZE_SUB", "1"); err != nil {
 		return err
 	}
@@ -185,6 +224,34 @@ func (e *Eletrize) startMany(signalChan <-chan os.Signal, args []string, onlySch
 		exitSignal   atomic.Bool
 	)
 
+	focus := newSchemaFocus(func(schema int) bool {
+		return e.Schema[schema-1].Commands.AcceptsInput()
+	})
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	sockets := make(map[int]string)
+	labels := make(map[int]string)
+
+	for i := range e.Schema {
+		if len(onlySchema) == 0 || slices.Contains(onlySchema, uint(i+1)) {
+			sockets[i+1] = control.SchemaSocketPath(wd, i+1)
+			labels[i+1] = schemaLabel(&e.Schema[i])
+		}
+	}
+
+	closeControl := serveControl(control.SocketPath(wd), control.ForwardHandler(sockets, labels))
+	defer closeControl()
+
+	k := e.manyKeyboard(focus, signalChan)
+	k.handleSignals()
+
+	restore := k.listen()
+	defer restore()
+
 	go func() {
 		<-signalChan
 
@@ -214,13 +281,17 @@ func (e *Eletrize) startMany(signalChan <-chan os.Signal, args []string, onlySch
 				log.Fatalf("PTY: %v", err)
 			}
 
+			_ = pty.InheritSize(os.Stdin, ptmx)
+
 			mu.Lock()
 			subprocesses = append(subprocesses, cmd)
 			mu.Unlock()
 
+			focus.add(index+1, ptmx)
+
 			defer func() { _ = ptmx.Close() }()
 
-			_, _ = io.Copy(os.Stdout, ptmx)
+			_, _ = io.Copy(focus.writer(index+1), ptmx)
 
 			if err := cmd.Wait(); err != nil && !exitSignal.Load() {
 				output.Pushf(output.LabelEletrize, "SCHEMA %d FINISHED: %s\n", index+1, err)
@@ -232,3 +303,102 @@ func (e *Eletrize) startMany(signalChan <-chan os.Signal, args []string, onlySch
 
 	return nil
 }
+
+// commandsStarted returns a function reporting whether commands have
+// started, since they can only be controlled from then on.
+func commandsStarted(commands *command.Commands) func() bool {
+	var started atomic.Bool
+
+	commands.Subscribe(func(command.Event) {
+		started.Store(true)
+	})
+
+	return started.Load
+}
+
+// serveControl serves the control API on socket, returning a function that
+// stops serving it. Eletrize runs without the API when it cannot be served.
+func serveControl(socket string, handler control.Handler) func() {
+	server, err := control.Listen(socket, handler)
+	if err != nil {
+		output.Pushf(output.LabelEletrize, "CONTROL API NOT AVAILABLE: %s\n", err)
+
+		return func() {}
+	}
+
+	return func() { _ = server.Close() }
+}
+
+func schemaLabel(s *schema.Schema) string {
+	if s.Label == nil {
+		return ""
+	}
+
+	return s.Label.Label
+}
+
+// schemaKeyboard returns the keyboard controls of a single schema. The
+// commands that reload the schema are ignored until it has started.
+func schemaKeyboard(s *schema.Schema, started func() bool, signalChan chan<- os.Signal) *keyboard {
+	whenStarted := func(fn func()) func() {
+		return func() {
+			if started() {
+				fn()
+			}
+		}
+	}
+
+	k := &keyboard{
+		commands: map[byte]func(){
+			'r': whenStarted(s.Commands.Restart),
+			'b': whenStarted(s.Commands.Rebuild),
+			'p': whenStarted(s.Commands.TogglePause),
+			'c': clearTerminal,
+			'q': func() { quit(signalChan) },
+		},
+	}
+
+	if s.Commands.AcceptsInput() {
+		k.input = s.Commands.SendInput
+	}
+
+	return k
+}
+
+// manyKeyboard returns the keyboard controls of several schemas, each one
+// running in a subprocess whose own keyboard receives the commands that
+// reload it.
+func (e *Eletrize) manyKeyboard(focus *schemaFocus, signalChan chan<- os.Signal) *keyboard {
+	broadcast := func(key byte) func() {
+		return func() {
+			focus.broadcast([]byte{keyPrefix, key})
+		}
+	}
+
+	k := &keyboard{
+		commands: map[byte]func(){
+			'r': broadcast('r'),
+			'b': broadcast('b'),
+			'p': broadcast('p'),
+			'c': clearTerminal,
+			'q': func() { quit(signalChan) },
+		},
+		focus: focus.focus,
+	}
+
+	if slices.ContainsFunc(e.Schema, func(s schema.Schema) bool {
+		return s.Commands.AcceptsInput()
+	}) {
+		k.input = focus.send
+	}
+
+	return k
+}
+
+// quit stops eletrize as if it had been interrupted.
+func quit(signalChan chan<- os.Signal) {
+	select {
+	case signalChan <- os.Interrupt:
+	default:
+	}
+}
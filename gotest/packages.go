@@ -0,0 +1,112 @@
+package gotest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"slices"
+)
+
+// goPackage is the subset of the output of "go list -json" needed to find
+// the packages affected by a change.
+type goPackage struct {
+	ImportPath   string
+	Dir          string
+	Deps         []string
+	TestImports  []string
+	XTestImports []string
+}
+
+// listPackages lists the packages of the module in dir. Packages with
+// errors are still listed, so a broken file does not hide its package.
+func listPackages(ctx context.Context, dir string) ([]goPackage, error) {
+	cmd := exec.CommandContext(ctx, "go", "list", "-e", "-json", "./...")
+	cmd.Dir = dir
+
+	data, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("go list: %s", exitErr.Stderr)
+		}
+
+		return nil, fmt.Errorf("go list: %w", err)
+	}
+
+	var packages []goPackage
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var pkg goPackage
+		if err := decoder.Decode(&pkg); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			return nil, fmt.Errorf("go list: %w", err)
+		}
+
+		packages = append(packages, pkg)
+	}
+
+	return packages, nil
+}
+
+// affectedPackages returns the import paths of the packages containing the
+// changed files and of every package depending on them, directly, through
+// other packages or from their tests. A change to go.mod or go.sum affects
+// every package.
+func affectedPackages(packages []goPackage, files []string) []string {
+	changed := make(map[string]bool)
+
+	for _, file := range files {
+		switch filepath.Base(file) {
+		case "go.mod", "go.sum":
+			return importPaths(packages, func(goPackage) bool { return true })
+		}
+
+		dir, err := filepath.Abs(filepath.Dir(file))
+		if err != nil {
+			continue
+		}
+
+		for _, pkg := range packages {
+			if pkg.Dir == dir {
+				changed[pkg.ImportPath] = true
+			}
+		}
+	}
+
+	affected := make(map[string]bool)
+
+	for _, pkg := range packages {
+		if changed[pkg.ImportPath] || slices.ContainsFunc(pkg.Deps, func(dep string) bool { return changed[dep] }) {
+			affected[pkg.ImportPath] = true
+		}
+	}
+
+	isAffected := func(path string) bool { return affected[path] }
+
+	return importPaths(packages, func(pkg goPackage) bool {
+		return affected[pkg.ImportPath] ||
+			slices.ContainsFunc(pkg.TestImports, isAffected) ||
+			slices.ContainsFunc(pkg.XTestImports, isAffected)
+	})
+}
+
+func importPaths(packages []goPackage, include func(goPackage) bool) []string {
+	var paths []string
+
+	for _, pkg := range packages {
+		if include(pkg) {
+			paths = append(paths, pkg.ImportPath)
+		}
+	}
+
+	return paths
+}
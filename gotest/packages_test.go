@@ -0,0 +1,49 @@
+package gotest
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestAffectedPackages(t *testing.T) {
+	root, err := filepath.Abs(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	packages := []goPackage{
+		{ImportPath: "m/a", Dir: filepath.Join(root, "a")},
+		{ImportPath: "m/b", Dir: filepath.Join(root, "b"), Deps: []string{"fmt", "m/a"}},
+		{ImportPath: "m/c", Dir: filepath.Join(root, "c"), Deps: []string{"m/a", "m/b"}},
+		{ImportPath: "m/d", Dir: filepath.Join(root, "d"), TestImports: []string{"m/b"}},
+		{ImportPath: "m/e", Dir: filepath.Join(root, "e"), XTestImports: []string{"m/d"}},
+	}
+
+	tests := []struct {
+		name     string
+		files    []string
+		expected []string
+	}{
+		{"Reverse dependencies", []string{"a/a.go"}, []string{"m/a", "m/b", "m/c", "m/d"}},
+		{"Test imports", []string{"./d/d.go"}, []string{"m/d", "m/e"}},
+		{"Leaf package", []string{"c/c_test.go"}, []string{"m/c"}},
+		{"Unknown directory", []string{"testdata/x.go"}, nil},
+		{"Module file", []string{"go.mod"}, []string{"m/a", "m/b", "m/c", "m/d", "m/e"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := affectedPackages(packages, tt.files)
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("affectedPackages(%q) = %q, want %q", tt.files, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRunPattern(t *testing.T) {
+	if got := runPattern([]string{"TestA", "TestB.C"}); got != `^(TestA|TestB\.C)$` {
+		t.Errorf("Unexpected pattern %q", got)
+	}
+}
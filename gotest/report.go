@@ -0,0 +1,168 @@
+package gotest
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lasfh/eletrize/output"
+)
+
+// testEvent is an event of "go test -json". Build events, which carry the
+// output of packages that failed to build, share the same stream.
+type testEvent struct {
+	Action      string
+	Package     string
+	Test        string
+	Output      string
+	FailedBuild string
+	ImportPath  string
+	Elapsed     float64
+}
+
+// report prints the results of "go test -json" compactly as they arrive:
+// one line per package, and the output of the failing tests only.
+type report struct {
+	label    *output.Label
+	outputs  map[string][]string
+	tested   []string
+	failures map[string][]string
+	passed   int
+	failed   int
+	skipped  int
+}
+
+func newReport(label *output.Label) *report {
+	return &report{
+		label:    label,
+		outputs:  make(map[string][]string),
+		failures: make(map[string][]string),
+	}
+}
+
+func (r *report) handle(event testEvent) {
+	switch event.Action {
+	case "build-output":
+		key := "build " + event.ImportPath
+		r.outputs[key] = append(r.outputs[key], event.Output)
+	case "build-fail":
+		output.Pushf(r.label, "BUILD FAILED %s\n", event.ImportPath)
+		r.printOutput("build " + event.ImportPath)
+	case "output":
+		key := event.Package + " " + event.Test
+		r.outputs[key] = append(r.outputs[key], event.Output)
+	case "pass", "fail", "skip":
+		if event.Test == "" {
+			r.handlePackage(event)
+		} else {
+			r.handleTest(event)
+		}
+	}
+}
+
+func (r *report) handleTest(event testEvent) {
+	key := event.Package + " " + event.Test
+	topLevel := !strings.Contains(event.Test, "/")
+
+	switch event.Action {
+	case "pass":
+		if topLevel {
+			r.passed++
+		}
+	case "skip":
+		if topLevel {
+			r.skipped++
+		}
+	case "fail":
+		output.Pushf(r.label, "--- FAIL: %s %s (%.2fs)\n", event.Package, event.Test, event.Elapsed)
+		r.printOutput(key)
+
+		if topLevel {
+			r.failed++
+			r.failures[event.Package] = append(r.failures[event.Package], event.Test)
+		}
+	}
+
+	delete(r.outputs, key)
+}
+
+func (r *report) handlePackage(event testEvent) {
+	key := event.Package + " "
+
+	switch event.Action {
+	case "pass":
+		r.tested = append(r.tested, event.Package)
+		output.Pushf(r.label, "ok   %s (%.2fs)\n", event.Package, event.Elapsed)
+	case "fail":
+		r.tested = append(r.tested, event.Package)
+		output.Pushf(r.label, "FAIL %s (%.2fs)\n", event.Package, event.Elapsed)
+
+		// A package that fails without failing tests, because it does not
+		// build or its test binary exits early, is rerun as a whole.
+		if _, ok := r.failures[event.Package]; !ok {
+			r.failures[event.Package] = []string{}
+
+			if event.FailedBuild == "" {
+				r.printOutput(key)
+			}
+		}
+	case "skip":
+		r.tested = append(r.tested, event.Package)
+	}
+
+	delete(r.outputs, key)
+}
+
+// printOutput prints the output collected for key, without the framing
+// lines of the test runner.
+func (r *report) printOutput(key string) {
+	for _, line := range r.outputs[key] {
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "", trimmed == "PASS", trimmed == "FAIL",
+			strings.HasPrefix(trimmed, "=== "),
+			strings.HasPrefix(trimmed, "--- "),
+			strings.HasPrefix(trimmed, "FAIL\t"),
+			strings.HasPrefix(trimmed, "ok  \t"):
+			continue
+		}
+
+		output.Pushf(nil, "    %s\n", strings.TrimRight(line, "\n"))
+	}
+
+	delete(r.outputs, key)
+}
+
+// brokenPackages counts the packages that failed without failing tests.
+func (r *report) brokenPackages() int {
+	var count int
+
+	for _, tests := range r.failures {
+		if len(tests) == 0 {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (r *report) summary(elapsed time.Duration) {
+	elapsed = elapsed.Round(time.Millisecond)
+
+	if r.failed > 0 || len(r.failures) > 0 {
+		var broken string
+		if count := r.brokenPackages(); count > 0 {
+			broken = fmt.Sprintf(", %d package(s) failed to build or run", count)
+		}
+
+		output.Alertf(
+			r.label, " FAIL: %d failed, %d passed, %d skipped%s in %s ",
+			r.failed, r.passed, r.skipped, broken, elapsed,
+		)
+
+		return
+	}
+
+	output.Pushf(r.label, "PASS: %d passed, %d skipped in %s\n", r.passed, r.skipped, elapsed)
+}
@@ -0,0 +1,338 @@
+package gotest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/lasfh/eletrize/command"
+	"github.com/lasfh/eletrize/output"
+	"github.com/lasfh/eletrize/watcher"
+)
+
+const changesDelay = 300 * time.Millisecond
+
+type Options struct {
+	// Path is the directory of the module, watched for changes.
+	Path string
+	// Args are extra flags passed to "go test", such as -race.
+	Args []string
+}
+
+// Runner runs the tests of the packages affected by each change. A run still
+// in progress when the next one is requested is canceled, and runs never
+// overlap.
+type Runner struct {
+	label   *output.Label
+	options Options
+
+	mu       sync.Mutex
+	ctx      context.Context
+	cancel   context.CancelFunc
+	changed  []string
+	pending  []string
+	timer    *time.Timer
+	failures map[string][]string
+
+	running sync.Mutex
+}
+
+func NewRunner(options Options, label *output.Label) *Runner {
+	if options.Path == "" {
+		options.Path = "."
+	}
+
+	return &Runner{
+		label:    output.LabelTest.Sub(label),
+		options:  options,
+		ctx:      context.Background(),
+		failures: make(map[string][]string),
+	}
+}
+
+// Run tests every package once, then the packages affected by each change,
+// until ctx is done.
+func (r *Runner) Run(ctx context.Context) error {
+	w, err := watcher.NewWatcher(watcher.Options{
+		Path:          r.options.Path,
+		Recursive:     true,
+		Extensions:    []string{".go", ".mod", ".sum"},
+		ExcludedPaths: []string{"vendor"},
+	})
+	if err != nil {
+		return err
+	}
+
+	defer w.Close()
+
+	if err := w.Start(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.ctx = ctx
+	r.mu.Unlock()
+
+	defer r.stop()
+
+	r.RunAll()
+
+	err = w.WatcherEvents(ctx, func(event fsnotify.Event, isDir bool) {
+		if !isDir {
+			r.fileChanged(event.Name)
+		}
+	})
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	return err
+}
+
+// RunAll tests every package of the module.
+func (r *Runner) RunAll() {
+	r.schedule(func(ctx context.Context) {
+		r.test(ctx, "ALL PACKAGES", []string{"./..."})
+	})
+}
+
+// RerunFailed runs again only the tests that failed in the previous runs.
+func (r *Runner) RerunFailed() {
+	r.schedule(func(ctx context.Context) {
+		r.mu.Lock()
+		failures := make(map[string][]string, len(r.failures))
+		for pkg, tests := range r.failures {
+			failures[pkg] = tests
+		}
+		r.mu.Unlock()
+
+		if len(failures) == 0 {
+			output.Pushf(r.label, "NO FAILING TESTS\n")
+
+			return
+		}
+
+		packages := make([]string, 0, len(failures))
+		for pkg := range failures {
+			packages = append(packages, pkg)
+		}
+
+		sort.Strings(packages)
+
+		output.Pushf(r.label, "RERUNNING FAILING TESTS OF %d PACKAGE(S)\n", len(packages))
+
+		started := time.Now()
+		rep := newReport(r.label)
+
+		for _, pkg := range packages {
+			args := []string{pkg}
+			if tests := failures[pkg]; len(tests) > 0 {
+				args = []string{"-run", runPattern(tests), pkg}
+			}
+
+			r.goTest(ctx, rep, args)
+
+			if ctx.Err() != nil {
+				output.Pushf(r.label, "CANCELED\n")
+
+				return
+			}
+		}
+
+		r.finish(rep, started)
+	})
+}
+
+func (r *Runner) fileChanged(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !slices.Contains(r.changed, name) {
+		r.changed = append(r.changed, name)
+	}
+
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+
+	r.timer = time.AfterFunc(changesDelay, r.testChanged)
+}
+
+// testChanged tests the packages affected by the changed files. The files of
+// a run that is canceled before it finishes are tested with the next ones.
+func (r *Runner) testChanged() {
+	r.mu.Lock()
+
+	for _, name := range r.changed {
+		if !slices.Contains(r.pending, name) {
+			r.pending = append(r.pending, name)
+		}
+	}
+
+	files := slices.Clone(r.pending)
+	r.changed = nil
+
+	r.mu.Unlock()
+
+	r.schedule(func(ctx context.Context) {
+		packages, err := listPackages(ctx, r.options.Path)
+		if err != nil {
+			output.Pushf(r.label, "%s\n", err)
+
+			return
+		}
+
+		affected := affectedPackages(packages, files)
+		if len(affected) == 0 {
+			output.Pushf(r.label, "NO PACKAGES AFFECTED BY %s\n", strings.Join(files, ", "))
+		} else {
+			r.test(ctx, strings.Join(affected, " "), affected)
+		}
+
+		if ctx.Err() == nil {
+			r.mu.Lock()
+			r.pending = slices.DeleteFunc(r.pending, func(name string) bool {
+				return slices.Contains(files, name)
+			})
+			r.mu.Unlock()
+		}
+	})
+}
+
+// schedule cancels the run in progress and runs job once it has finished.
+func (r *Runner) schedule(job func(ctx context.Context)) {
+	r.mu.Lock()
+
+	if r.cancel != nil {
+		r.cancel()
+	}
+
+	ctx, cancel := context.WithCancel(r.ctx)
+	r.cancel = cancel
+
+	r.mu.Unlock()
+
+	go func() {
+		r.running.Lock()
+		defer r.running.Unlock()
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		job(ctx)
+	}()
+}
+
+// stop cancels the run in progress and waits for it to finish.
+func (r *Runner) stop() {
+	r.mu.Lock()
+
+	if r.cancel != nil {
+		r.cancel()
+	}
+
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+
+	r.mu.Unlock()
+
+	r.running.Lock()
+	defer r.running.Unlock()
+}
+
+func (r *Runner) test(ctx context.Context, description string, packages []string) {
+	output.Pushf(r.label, "TESTING %s\n", description)
+
+	started := time.Now()
+	rep := newReport(r.label)
+
+	r.goTest(ctx, rep, packages)
+
+	if ctx.Err() != nil {
+		output.Pushf(r.label, "CANCELED\n")
+
+		return
+	}
+
+	r.finish(rep, started)
+}
+
+// finish records the failures of the tested packages and prints the summary.
+func (r *Runner) finish(rep *report, started time.Time) {
+	r.mu.Lock()
+
+	for _, pkg := range rep.tested {
+		delete(r.failures, pkg)
+	}
+
+	for pkg, tests := range rep.failures {
+		r.failures[pkg] = tests
+	}
+
+	r.mu.Unlock()
+
+	rep.summary(time.Since(started))
+}
+
+// goTest runs "go test -json" with args and reports its events.
+func (r *Runner) goTest(ctx context.Context, rep *report, args []string) {
+	cmd := command.NewProcess("go", append(append([]string{"test", "-json"}, r.options.Args...), args...)...)
+	cmd.Dir = r.options.Path
+	cmd.Stderr = os.Stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		output.Pushf(r.label, "ERROR: %s\n", err)
+
+		return
+	}
+
+	if err := cmd.Start(); err != nil {
+		output.Pushf(r.label, "ERROR: %s\n", err)
+
+		return
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		_ = command.InterruptProcess(cmd)
+	})
+	defer stop()
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	for scanner.Scan() {
+		var event testEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			output.Pushf(nil, "%s\n", scanner.Text())
+
+			continue
+		}
+
+		rep.handle(event)
+	}
+
+	_ = cmd.Wait()
+}
+
+// runPattern returns the -run pattern matching exactly the given top-level
+// tests.
+func runPattern(tests []string) string {
+	quoted := make([]string, len(tests))
+	for i, test := range tests {
+		quoted[i] = regexp.QuoteMeta(test)
+	}
+
+	return "^(" + strings.Join(quoted, "|") + ")$"
+}
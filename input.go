@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+
+	"github.com/lasfh/eletrize/output"
+)
+
+const (
+	// keyPrefix is Ctrl+T. When the keys are sent to a run command, the
+	// commands of eletrize are typed after it.
+	keyPrefix = 0x14
+
+	clearScreen = "\033[H\033[2J\033[3J"
+)
+
+// keyboard runs the commands of the keys typed in the terminal. When a run
+// command reads the input, the keys are sent to it instead, the commands are
+// typed after Ctrl+T, and Ctrl+T twice sends Ctrl+T itself.
+type keyboard struct {
+	input    func(input []byte)
+	commands map[byte]func()
+	focus    func(schema int)
+	prefix   bool
+}
+
+// listen reads the keys typed in the terminal, as soon as they are typed,
+// until stdin is closed and returns a function restoring the terminal.
+// Nothing is read unless stdin is a terminal and eletrize runs in its
+// foreground: piped input is not taken for keys, and reading the terminal
+// from the background would stop eletrize.
+func (k *keyboard) listen() (restore func()) {
+	restore = func() {}
+
+	fd := int(os.Stdin.Fd())
+	if !isTerminal(fd) || !isForeground(fd) {
+		return restore
+	}
+
+	var err error
+	if restore, err = makeCbreak(fd); err != nil {
+		output.Pushf(output.LabelEletrize, "KEYBOARD: %s\n", err)
+
+		return func() {}
+	}
+
+	go k.read(os.Stdin)
+
+	return restore
+}
+
+// handleSignals runs the commands of the control signals received, as if
+// their keys had been pressed.
+func (k *keyboard) handleSignals() {
+	if len(controlSignals) == 0 {
+		return
+	}
+
+	signals := make(chan os.Signal, 1)
+	for sig := range controlSignals {
+		signal.Notify(signals, sig)
+	}
+
+	go func() {
+		for sig := range signals {
+			output.Pushf(output.LabelEletrize, "RECEIVED %s\n", strings.ToUpper(sig.String()))
+
+			k.run(controlSignals[sig])
+		}
+	}()
+}
+
+func (k *keyboard) read(r io.Reader) {
+	buf := make([]byte, 1024)
+
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			k.handle(buf[:n])
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (k *keyboard) handle(keys []byte) {
+	var input []byte
+
+	for _, key := range keys {
+		switch {
+		case k.prefix:
+			k.prefix = false
+
+			if key == keyPrefix && k.input != nil {
+				input = append(input, key)
+
+				continue
+			}
+
+			k.send(input)
+			input = nil
+
+			k.run(key)
+		case key == keyPrefix:
+			k.prefix = true
+		case k.input != nil:
+			input = append(input, key)
+		default:
+			k.run(key)
+		}
+	}
+
+	k.send(input)
+}
+
+func (k *keyboard) send(input []byte) {
+	if len(input) > 0 {
+		k.input(input)
+	}
+}
+
+func (k *keyboard) run(key byte) {
+	if key >= '1' && key <= '9' {
+		if k.focus != nil {
+			k.focus(int(key - '0'))
+		}
+
+		return
+	}
+
+	if command, ok := k.commands[key]; ok {
+		command()
+	}
+}
+
+func clearTerminal() {
+	fmt.Print(clearScreen)
+}
+
+// schemaFocus tracks the schema in focus when several schemas run at once:
+// only its output is shown, and the input goes to the schema in focus that
+// last read input.
+type schemaFocus struct {
+	mu      sync.Mutex
+	accepts func(schema int) bool
+	inputs  map[int]io.Writer
+	input   int
+	output  int
+}
+
+func newSchemaFocus(accepts func(schema int) bool) *schemaFocus {
+	return &schemaFocus{
+		accepts: accepts,
+		inputs:  make(map[int]io.Writer),
+	}
+}
+
+// add registers the input of a schema, sending the input to it when it is
+// the first schema that reads input.
+func (f *schemaFocus) add(schema int, input io.Writer) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.inputs[schema] = input
+
+	if (f.input == 0 || f.input > schema) && f.accepts(schema) {
+		f.input = schema
+	}
+}
+
+// send writes the input to the schema that reads it.
+func (f *schemaFocus) send(input []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if w, ok := f.inputs[f.input]; ok {
+		_, _ = w.Write(input)
+	}
+}
+
+// broadcast writes the input to every schema.
+func (f *schemaFocus) broadcast(input []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, w := range f.inputs {
+		_, _ = w.Write(input)
+	}
+}
+
+// focus shows only the output of schema, or the output of every schema when
+// it is already in focus.
+func (f *schemaFocus) focus(schema int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.inputs[schema]; !ok {
+		output.Pushf(output.LabelEletrize, "SCHEMA %d IS NOT RUNNING\n", schema)
+
+		return
+	}
+
+	if f.output == schema {
+		f.output = 0
+
+		output.Pushf(output.LabelEletrize, "SHOWING THE OUTPUT OF EVERY SCHEMA\n")
+
+		return
+	}
+
+	f.output = schema
+
+	output.Pushf(
+		output.LabelEletrize,
+		"SHOWING ONLY THE OUTPUT OF SCHEMA %d, PRESS %d AGAIN TO SHOW EVERY SCHEMA\n",
+		schema, schema,
+	)
+
+	if f.input != schema && f.accepts(schema) {
+		f.input = schema
+
+		output.Pushf(output.LabelEletrize, "INPUT SENT TO SCHEMA %d\n", schema)
+	}
+}
+
+// writer returns the writer of the output of schema, which discards it
+// while another schema is in focus.
+func (f *schemaFocus) writer(schema int) io.Writer {
+	return focusedOutput{focus: f, schema: schema}
+}
+
+type focusedOutput struct {
+	focus  *schemaFocus
+	schema int
+}
+
+func (o focusedOutput) Write(p []byte) (int, error) {
+	o.focus.mu.Lock()
+	shown := o.focus.output == 0 || o.focus.output == o.schema
+	o.focus.mu.Unlock()
+
+	if !shown {
+		return len(p), nil
+	}
+
+	return os.Stdout.Write(p)
+}
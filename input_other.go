@@ -0,0 +1,17 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd && !windows
+
+package main
+
+import "errors"
+
+func isTerminal(fd int) bool {
+	return false
+}
+
+func isForeground(fd int) bool {
+	return false
+}
+
+func makeCbreak(fd int) (func(), error) {
+	return nil, errors.New("terminal input not supported on this platform")
+}
@@ -0,0 +1,44 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package main
+
+import "golang.org/x/sys/unix"
+
+func isTerminal(fd int) bool {
+	_, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+
+	return err == nil
+}
+
+// isForeground reports whether eletrize is in the foreground process group
+// of the terminal fd.
+func isForeground(fd int) bool {
+	pgrp, err := unix.IoctlGetInt(fd, unix.TIOCGPGRP)
+
+	return err == nil && pgrp == unix.Getpgrp()
+}
+
+// makeCbreak makes the keys typed in the terminal readable as soon as they
+// are typed, without echoing them, and returns a function restoring the
+// terminal. Unlike the raw mode, the output is still processed and Ctrl+C
+// still interrupts eletrize.
+func makeCbreak(fd int) (func(), error) {
+	termios, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := *termios
+
+	termios.Lflag &^= unix.ECHO | unix.ICANON | unix.IEXTEN
+	termios.Cc[unix.VMIN] = 1
+	termios.Cc[unix.VTIME] = 0
+
+	if err := unix.IoctlSetTermios(fd, ioctlSetTermios, termios); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		_ = unix.IoctlSetTermios(fd, ioctlSetTermios, &previous)
+	}, nil
+}
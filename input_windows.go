@@ -0,0 +1,35 @@
+package main
+
+import "golang.org/x/sys/windows"
+
+func isTerminal(fd int) bool {
+	var mode uint32
+
+	return windows.GetConsoleMode(windows.Handle(fd), &mode) == nil
+}
+
+// isForeground reports true, since a console has no background processes.
+func isForeground(fd int) bool {
+	return true
+}
+
+// makeCbreak makes the keys typed in the console readable as soon as they
+// are typed, without echoing them, and returns a function restoring the
+// console. Ctrl+C still interrupts eletrize.
+func makeCbreak(fd int) (func(), error) {
+	handle := windows.Handle(fd)
+
+	var previous uint32
+	if err := windows.GetConsoleMode(handle, &previous); err != nil {
+		return nil, err
+	}
+
+	mode := previous &^ (windows.ENABLE_LINE_INPUT | windows.ENABLE_ECHO_INPUT)
+	if err := windows.SetConsoleMode(handle, mode); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		_ = windows.SetConsoleMode(handle, previous)
+	}, nil
+}
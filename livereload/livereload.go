@@ -0,0 +1,230 @@
+package livereload
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/lasfh/eletrize/command"
+	"github.com/lasfh/eletrize/output"
+)
+
+const (
+	// PathPrefix is the path under which the live reload endpoints are served.
+	PathPrefix = "/__eletrize/"
+	// ScriptPath is the path of the client script.
+	ScriptPath = PathPrefix + "livereload.js"
+	// EventsPath is the path of the Server-Sent Events endpoint.
+	EventsPath = PathPrefix + "events"
+
+	EventReload = "reload"
+	EventCSS    = "css"
+
+	defaultListen = ":35729"
+	assetsDelay   = 100 * time.Millisecond
+)
+
+//go:embed livereload.js
+var clientScript []byte
+
+type Options struct {
+	Listen string   `json:"listen" yaml:"listen"`
+	Assets []string `json:"assets" yaml:"assets"`
+}
+
+// Server broadcasts reload events to the connected browsers.
+type Server struct {
+	label   *output.Label
+	options Options
+	server  *http.Server
+	proxied bool
+
+	mu      sync.Mutex
+	clients map[chan string]struct{}
+	changed []string
+	timer   *time.Timer
+}
+
+func NewServer(options Options, label *output.Label) *Server {
+	s := &Server{
+		label:   output.LabelLiveReload.Sub(label),
+		options: options,
+		clients: make(map[chan string]struct{}),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(ScriptPath, s.serveScript)
+	mux.HandleFunc(EventsPath, s.serveEvents)
+
+	s.server = &http.Server{
+		Handler: mux,
+	}
+
+	return s
+}
+
+// Handler returns the handler of the live reload endpoints, so they can be
+// served by the development proxy. Once it is called, the server considers
+// the pages to be served through the proxy.
+func (s *Server) Handler() http.Handler {
+	s.proxied = true
+
+	return s.server.Handler
+}
+
+// Start listens on its own address when one is configured or when the
+// endpoints are not served by the development proxy.
+func (s *Server) Start() error {
+	listen := s.options.Listen
+	if listen == "" {
+		if s.proxied {
+			return nil
+		}
+
+		listen = defaultListen
+	}
+
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return fmt.Errorf("live_reload: %w", err)
+	}
+
+	output.Pushf(s.label, "SCRIPT AVAILABLE AT http://%s%s\n", listener.Addr(), ScriptPath)
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			output.Pushf(s.label, "ERROR: %s\n", err)
+		}
+	}()
+
+	return nil
+}
+
+func (s *Server) Close() error {
+	return s.server.Close()
+}
+
+// Notify reloads the browsers after a successful restart. When the pages are
+// served by the development proxy, they are also reloaded after a failed
+// build, so the build error is displayed.
+func (s *Server) Notify(event command.Event) {
+	switch event.Type {
+	case command.EventStarted:
+		s.broadcast(EventReload)
+	case command.EventBuildFailed:
+		// The error page is only served when the previous version stopped.
+		if s.proxied && !event.KeptAlive {
+			s.broadcast(EventReload)
+		}
+	}
+}
+
+// IsAsset reports whether the file is a static asset, whose changes refresh
+// the browser without rebuilding and restarting the commands.
+func (s *Server) IsAsset(name string) bool {
+	return slices.Contains(s.options.Assets, filepath.Ext(name))
+}
+
+// AssetChanged refreshes the browsers after a static asset changes. Changes
+// are grouped, and when only stylesheets changed they are swapped in place
+// instead of reloading the page.
+func (s *Server) AssetChanged(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.changed = append(s.changed, name)
+
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+
+	s.timer = time.AfterFunc(assetsDelay, s.flushAssets)
+}
+
+func (s *Server) flushAssets() {
+	s.mu.Lock()
+	changed := s.changed
+	s.changed = nil
+	s.mu.Unlock()
+
+	event := EventCSS
+
+	for _, name := range changed {
+		if filepath.Ext(name) != ".css" {
+			event = EventReload
+
+			break
+		}
+	}
+
+	s.broadcast(event)
+}
+
+func (s *Server) broadcast(event string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.clients) == 0 {
+		return
+	}
+
+	output.Pushf(s.label, "SENDING %s TO %d CLIENT(S)\n", event, len(s.clients))
+
+	for client := range s.clients {
+		select {
+		case client <- event:
+		default:
+		}
+	}
+}
+
+func (s *Server) serveScript(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+
+	_, _ = w.Write(clientScript)
+}
+
+func (s *Server) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	client := make(chan string, 1)
+
+	s.mu.Lock()
+	s.clients[client] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, client)
+		s.mu.Unlock()
+	}()
+
+	_, _ = fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	for {
+		select {
+		case event := <-client:
+			_, _ = fmt.Fprintf(w, "event: %s\ndata: {}\n\n", event)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
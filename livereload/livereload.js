@@ -0,0 +1,48 @@
+(function () {
+  "use strict";
+
+  var script = document.currentScript;
+  var origin = script && script.src ? new URL(script.src).origin : location.origin;
+
+  // waitAndReload reloads the page once the server answers again, since the
+  // reload event may arrive before the restarted server accepts connections.
+  function waitAndReload() {
+    fetch(location.href, { method: "HEAD", cache: "no-store" })
+      .then(function () {
+        location.reload();
+      })
+      .catch(function () {
+        setTimeout(waitAndReload, 250);
+      });
+  }
+
+  function swapStylesheets() {
+    var links = document.querySelectorAll('link[rel="stylesheet"]');
+
+    Array.prototype.forEach.call(links, function (link) {
+      var url = new URL(link.href, location.href);
+      url.searchParams.set("eletrize", Date.now().toString());
+
+      var next = link.cloneNode();
+      next.href = url.toString();
+      next.onload = function () {
+        link.remove();
+      };
+
+      link.after(next);
+    });
+  }
+
+  function connect() {
+    var source = new EventSource(origin + "/__eletrize/events");
+
+    source.addEventListener("reload", waitAndReload);
+    source.addEventListener("css", swapStylesheets);
+    source.onerror = function () {
+      source.close();
+      setTimeout(connect, 1000);
+    };
+  }
+
+  connect();
+})();
@@ -0,0 +1,103 @@
+package livereload
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lasfh/eletrize/command"
+)
+
+func TestServer_IsAsset(t *testing.T) {
+	s := NewServer(Options{Assets: []string{".css", ".html"}}, nil)
+
+	tests := []struct {
+		name     string
+		expected bool
+	}{
+		{"static/app.css", true},
+		{"templates/index.html", true},
+		{"main.go", false},
+		{"css", false},
+	}
+
+	for _, tt := range tests {
+		if got := s.IsAsset(tt.name); got != tt.expected {
+			t.Errorf("IsAsset(%q) = %v, want %v", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestServer_Events(t *testing.T) {
+	s := NewServer(Options{Assets: []string{".css", ".js"}}, nil)
+
+	server := httptest.NewServer(s.server.Handler)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + EventsPath)
+	if err != nil {
+		t.Fatalf("Failed to connect to the events endpoint: %v", err)
+	}
+
+	defer resp.Body.Close()
+
+	events := make(chan string)
+
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if event, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+				events <- event
+			}
+		}
+	}()
+
+	waitClient(t, s)
+
+	expect := func(expected string) {
+		t.Helper()
+
+		select {
+		case event := <-events:
+			if event != expected {
+				t.Errorf("Expected event %q, got %q", expected, event)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Event %q was not received", expected)
+		}
+	}
+
+	s.AssetChanged("static/app.css")
+	s.AssetChanged("static/theme.css")
+	expect(EventCSS)
+
+	s.AssetChanged("static/app.css")
+	s.AssetChanged("static/app.js")
+	expect(EventReload)
+
+	s.Notify(command.Event{Type: command.EventStarted})
+	expect(EventReload)
+}
+
+func waitClient(t *testing.T, s *Server) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+
+	for time.Now().Before(deadline) {
+		s.mu.Lock()
+		connected := len(s.clients) > 0
+		s.mu.Unlock()
+
+		if connected {
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatal("Client did not connect")
+}
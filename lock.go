@@ -3,16 +3,48 @@ package main
 import (
 	"errors"
 	"os"
+	"path/filepath"
+
+	"github.com/lasfh/eletrize/output"
+	"github.com/lasfh/eletrize/workspace"
 )
 
-func lock() error {
-	if os.Getenv("ELETRIZE_LOCKED") == "" {
-		return os.Setenv("ELETRIZE_LOCKED", "1")
+// lock makes sure eletrize is not already running in the current directory,
+// nor inside a command started by another eletrize, and returns a function
+// releasing the lock. The subprocesses running the schemas share the lock
+// of their parent.
+func lock(config string) (func(), error) {
+	if os.Getenv("ELETRIZE_LOCKED") != "" {
+		if os.Getenv("ELETRIZE_SUB") == "1" {
+			return func() {}, nil
+		}
+
+		return nil, errors.New("program is already running in this directory. Please close it before starting a new one")
+	}
+
+	if err := os.Setenv("ELETRIZE_LOCKED", "1"); err != nil {
+		return nil, err
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	if config != "" {
+		if abs, err := filepath.Abs(config); err == nil {
+			config = abs
+		}
+	}
+
+	l, err := workspace.Acquire(wd, config)
+	if err != nil {
+		return nil, err
 	}
 
-	if os.Getenv("ELETRIZE_SUB") == "1" {
-		return nil
+	if l.Stale != nil {
+		output.Pushf(output.LabelEletrize, "REPLACED STALE LOCK OF %s\n", l.Stale)
 	}
 
-	return errors.New("program is already running in this directory. Please close it before starting a new one")
+	return func() { _ = l.Release() }, nil
 }
@@ -56,6 +56,36 @@ var (
 			Color: color.New(color.FgRed),
 		},
 	}
+	LabelRun = DefaultLabel{
+		Label: Label{
+			Label: "RUN",
+			Color: color.New(color.FgGreen),
+		},
+	}
+	LabelProxy = DefaultLabel{
+		Label: Label{
+			Label: "PROXY",
+			Color: color.New(color.FgBlue),
+		},
+	}
+	LabelLiveReload = DefaultLabel{
+		Label: Label{
+			Label: "LIVE RELOAD",
+			Color: color.New(color.FgHiBlue),
+		},
+	}
+	LabelHook = DefaultLabel{
+		Label: Label{
+			Label: "HOOK",
+			Color: color.New(color.FgCyan),
+		},
+	}
+	LabelTest = DefaultLabel{
+		Label: Label{
+			Label: "TEST",
+			Color: color.New(color.FgHiMagenta),
+		},
+	}
 )
 
 func (l *Label) UnmarshalYAML(value *yaml.Node) error {
@@ -126,6 +156,19 @@ func (l DefaultLabel) Sub(label *Label) *Label {
 	return &l.Label
 }
 
+var alertColor = color.New(color.FgHiWhite, color.BgRed, color.Bold)
+
+// Alertf prints a highlighted message, used for events that must not be
+// missed among the output of the commands.
+func Alertf(label *Label, format string, a ...any) {
+	if label != nil {
+		label.Color.Fprintf(color.Output, "[%s] ", label.Label)
+	}
+
+	alertColor.Fprintf(color.Output, format, a...)
+	fmt.Fprintln(os.Stdout)
+}
+
 func Push(label *Label, output string) {
 	if label != nil {
 		label.Color.Fprintf(color.Output, "[%s] ", label.Label)
@@ -0,0 +1,49 @@
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/lasfh/eletrize/livereload"
+)
+
+var scriptTag = []byte(`<script src="` + livereload.ScriptPath + `"></script>`)
+
+// injectScript adds the live reload client script to the HTML responses,
+// right before the closing body tag or at the end of the document.
+func (p *Proxy) injectScript(resp *http.Response) error {
+	if p.liveReload == nil || resp.Header.Get("Content-Encoding") != "" {
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" {
+		return nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	if err != nil {
+		return err
+	}
+
+	index := bytes.LastIndex(bytes.ToLower(body), []byte("</body>"))
+	if index < 0 {
+		index = len(body)
+	}
+
+	injected := make([]byte, 0, len(body)+len(scriptTag))
+	injected = append(injected, body[:index]...)
+	injected = append(injected, scriptTag...)
+	injected = append(injected, body[index:]...)
+
+	resp.Body = io.NopCloser(bytes.NewReader(injected))
+	resp.ContentLength = int64(len(injected))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(injected)))
+
+	return nil
+}
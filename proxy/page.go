@@ -0,0 +1,40 @@
+package proxy
+
+import (
+	"html/template"
+	"net/http"
+)
+
+var errorPage = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}} - Eletrize</title>
+<style>
+  body { margin: 0; padding: 2rem; background: #1e1e2e; color: #cdd6f4; font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; }
+  h1 { margin-top: 0; color: #f38ba8; font-size: 1.5rem; }
+  .label { color: #cba6f7; font-weight: bold; letter-spacing: .1em; }
+  .error { color: #fab387; }
+  pre { background: #11111b; padding: 1rem; border-radius: 6px; overflow: auto; line-height: 1.4; }
+</style>
+</head>
+<body>
+<p class="label">ELETRIZE</p>
+<h1>{{.Title}}</h1>
+{{if .Err}}<p class="error">{{.Err}}</p>{{end}}
+{{if .Output}}<pre>{{.Output}}</pre>{{end}}
+</body>
+</html>
+`))
+
+func renderError(w http.ResponseWriter, status int, title string, err error, output string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+
+	_ = errorPage.Execute(w, struct {
+		Title  string
+		Err    error
+		Output string
+	}{title, err, output})
+}
@@ -0,0 +1,313 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lasfh/eletrize/command"
+	"github.com/lasfh/eletrize/livereload"
+	"github.com/lasfh/eletrize/output"
+)
+
+const (
+	// holdTimeout is the maximum time a request is held while the backend
+	// is building or restarting.
+	holdTimeout = 60 * time.Second
+	// readyTimeout is the maximum time waited for the backend to accept
+	// connections after the run commands are started.
+	readyTimeout  = 30 * time.Second
+	probeInterval = 100 * time.Millisecond
+)
+
+const (
+	stateStarting state = iota
+	stateReady
+	stateFailed
+)
+
+var ErrTargetIsEmpty = errors.New("proxy: target is empty")
+
+type state int
+
+type Options struct {
+	Listen string `json:"listen" yaml:"listen"`
+	Target string `json:"target" yaml:"target"`
+}
+
+// Proxy is a development reverse proxy that stays up while the backend
+// restarts. The running backend is proxied while it is rebuilt, and requests
+// received once it is stopped are held until the new one is ready, or
+// answered with the build error when the build fails.
+type Proxy struct {
+	label   *output.Label
+	target  *url.URL
+	address string
+	server  *http.Server
+	reverse *httputil.ReverseProxy
+
+	liveReload http.Handler
+
+	mu         sync.Mutex
+	state      state
+	err        error
+	output     string
+	generation uint64
+	changed    chan struct{}
+}
+
+func NewProxy(options Options, label *output.Label) (*Proxy, error) {
+	target, err := parseTarget(options.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	listen := options.Listen
+	if listen == "" {
+		listen = ":3000"
+	}
+
+	address := target.Host
+	if target.Port() == "" {
+		port := "80"
+		if target.Scheme == "https" {
+			port = "443"
+		}
+
+		address = net.JoinHostPort(target.Hostname(), port)
+	}
+
+	p := &Proxy{
+		label:   output.LabelProxy.Sub(label),
+		target:  target,
+		address: address,
+		changed: make(chan struct{}),
+	}
+
+	p.reverse = httputil.NewSingleHostReverseProxy(target)
+	p.reverse.ErrorHandler = p.handleBackendError
+	p.reverse.ModifyResponse = p.injectScript
+
+	director := p.reverse.Director
+	p.reverse.Director = func(r *http.Request) {
+		director(r)
+
+		if p.liveReload != nil {
+			// The response must not be compressed, so the script can be
+			// injected into the HTML pages.
+			r.Header.Del("Accept-Encoding")
+		}
+	}
+	p.server = &http.Server{
+		Addr:    listen,
+		Handler: p,
+	}
+
+	return p, nil
+}
+
+// parseTarget accepts a port (":8080"), a host and port ("localhost:8080")
+// or a full URL.
+func parseTarget(target string) (*url.URL, error) {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return nil, ErrTargetIsEmpty
+	}
+
+	if strings.HasPrefix(target, ":") {
+		target = "localhost" + target
+	}
+
+	if !strings.Contains(target, "://") {
+		target = "http://" + target
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("proxy: target: %w", err)
+	}
+
+	return u, nil
+}
+
+// Start starts listening for requests in the background.
+func (p *Proxy) Start() error {
+	listener, err := net.Listen("tcp", p.server.Addr)
+	if err != nil {
+		return fmt.Errorf("proxy: %w", err)
+	}
+
+	output.Pushf(p.label, "LISTENING ON %s -> %s\n", listener.Addr(), p.target)
+
+	go func() {
+		if err := p.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			output.Pushf(p.label, "ERROR: %s\n", err)
+		}
+	}()
+
+	return nil
+}
+
+func (p *Proxy) Close() error {
+	return p.server.Close()
+}
+
+// Notify updates the state of the proxy from the events of the reload cycle.
+func (p *Proxy) Notify(event command.Event) {
+	switch event.Type {
+	case command.EventBuildStarted:
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		// The previous version keeps being proxied until it is stopped.
+		if p.state == stateFailed {
+			p.state, p.err, p.output = stateStarting, nil, ""
+			p.broadcast()
+		}
+	case command.EventStopped:
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		// A failed build stops the previous version, whose requests are
+		// answered with the build error.
+		if p.state != stateFailed {
+			p.state = stateStarting
+			p.broadcast()
+		}
+	case command.EventBuildFailed:
+		// With keep_alive_on_build_failure, the previous version is still
+		// running and keeps being proxied.
+		if !event.KeptAlive {
+			p.setState(stateFailed, event.Err, event.Output)
+		}
+	case command.EventUnchanged:
+		p.mu.Lock()
+		ready := p.state == stateReady
+		p.mu.Unlock()
+
+		// The run commands kept running without a restart.
+		if ready {
+			return
+		}
+
+		fallthrough
+	case command.EventStarted:
+		generation := p.setState(stateStarting, nil, "")
+
+		go p.waitUntilReady(generation)
+	}
+}
+
+func (p *Proxy) setState(s state, err error, buildOutput string) uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.state = s
+	p.err = err
+	p.output = buildOutput
+	p.broadcast()
+
+	return p.generation
+}
+
+// broadcast wakes up the held requests after a state change. It must be
+// called with p.mu held.
+func (p *Proxy) broadcast() {
+	p.generation++
+
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+// waitUntilReady probes the target until it accepts connections. The probe
+// is abandoned when the state changes in the meantime. A socket opened by
+// eletrize with listen accepts connections even before the backend does, and
+// the requests then wait for it in the queue of the socket.
+func (p *Proxy) waitUntilReady(generation uint64) {
+	deadline := time.Now().Add(readyTimeout)
+
+	for time.Now().Before(deadline) {
+		conn, err := net.DialTimeout("tcp", p.address, probeInterval)
+		if err == nil {
+			_ = conn.Close()
+
+			break
+		}
+
+		time.Sleep(probeInterval)
+
+		p.mu.Lock()
+		current := p.generation
+		p.mu.Unlock()
+
+		if current != generation {
+			return
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.generation == generation {
+		p.state = stateReady
+		p.broadcast()
+	}
+}
+
+// EnableLiveReload serves the live reload endpoints through the proxy and
+// injects the client script into the HTML pages. It must be called before
+// Start.
+func (p *Proxy) EnableLiveReload(handler http.Handler) {
+	p.liveReload = handler
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if p.liveReload != nil && strings.HasPrefix(r.URL.Path, livereload.PathPrefix) {
+		p.liveReload.ServeHTTP(w, r)
+
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), holdTimeout)
+	defer cancel()
+
+	for {
+		p.mu.Lock()
+		current, err, buildOutput, changed := p.state, p.err, p.output, p.changed
+		p.mu.Unlock()
+
+		switch current {
+		case stateReady:
+			p.reverse.ServeHTTP(w, r)
+
+			return
+		case stateFailed:
+			renderError(w, http.StatusInternalServerError, "Build failed", err, buildOutput)
+
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			renderError(w, http.StatusGatewayTimeout, "Backend is not ready", ctx.Err(), "")
+
+			return
+		}
+	}
+}
+
+func (p *Proxy) handleBackendError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	renderError(w, http.StatusBadGateway, "Backend is unavailable", err, "")
+}
@@ -0,0 +1,210 @@
+package proxy
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lasfh/eletrize/command"
+)
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   string
+		expected string
+	}{
+		{"Port only", ":8080", "http://localhost:8080"},
+		{"Host and port", "127.0.0.1:8080", "http://127.0.0.1:8080"},
+		{"Full URL", "https://example.com:8443", "https://example.com:8443"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTarget(tt.target)
+			if err != nil {
+				t.Fatalf("parseTarget(%q) unexpected error: %v", tt.target, err)
+			}
+
+			if got.String() != tt.expected {
+				t.Errorf("parseTarget(%q) = %q, want %q", tt.target, got, tt.expected)
+			}
+		})
+	}
+
+	if _, err := parseTarget(" "); !errors.Is(err, ErrTargetIsEmpty) {
+		t.Errorf("Expected ErrTargetIsEmpty, got %v", err)
+	}
+}
+
+func TestProxy_HoldsRequestsUntilReady(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "backend")
+	}))
+	defer backend.Close()
+
+	p, err := NewProxy(Options{Target: backend.URL}, nil)
+	if err != nil {
+		t.Fatalf("NewProxy returned error: %v", err)
+	}
+
+	p.Notify(command.Event{Type: command.EventBuildStarted})
+
+	done := make(chan *httptest.ResponseRecorder)
+
+	go func() {
+		recorder := httptest.NewRecorder()
+		p.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+		done <- recorder
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("Request should be held while building")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	p.Notify(command.Event{Type: command.EventStarted})
+
+	select {
+	case recorder := <-done:
+		if recorder.Body.String() != "backend" {
+			t.Errorf("Expected backend response, got %q", recorder.Body.String())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Request was not released after the backend became ready")
+	}
+}
+
+func TestProxy_ProxiesWhileBuilding(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "previous")
+	}))
+	defer backend.Close()
+
+	p, err := NewProxy(Options{Target: backend.URL}, nil)
+	if err != nil {
+		t.Fatalf("NewProxy returned error: %v", err)
+	}
+
+	p.Notify(command.Event{Type: command.EventStarted})
+
+	// Waits for the backend to be ready.
+	recorder := httptest.NewRecorder()
+	p.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	// The running version is proxied until it is stopped to restart.
+	p.Notify(command.Event{Type: command.EventBuildStarted})
+
+	recorder = httptest.NewRecorder()
+	p.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "previous" {
+		t.Errorf("Expected the running version to be proxied during the build, got %d %q", recorder.Code, recorder.Body.String())
+	}
+
+	p.Notify(command.Event{Type: command.EventStopped})
+
+	p.mu.Lock()
+	current := p.state
+	p.mu.Unlock()
+
+	if current != stateStarting {
+		t.Errorf("Expected the requests to be held once the backend is stopped, got state %d", current)
+	}
+}
+
+func TestProxy_BuildFailed(t *testing.T) {
+	p, err := NewProxy(Options{Target: ":1"}, nil)
+	if err != nil {
+		t.Fatalf("NewProxy returned error: %v", err)
+	}
+
+	p.Notify(command.Event{
+		Type:   command.EventBuildFailed,
+		Err:    errors.New("exit status 1"),
+		Output: "main.go:1: syntax error <here>",
+	})
+	p.Notify(command.Event{Type: command.EventStopped})
+
+	recorder := httptest.NewRecorder()
+	p.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, recorder.Code)
+	}
+
+	body := recorder.Body.String()
+	if !strings.Contains(body, "exit status 1") || !strings.Contains(body, "syntax error &lt;here&gt;") {
+		t.Errorf("Expected escaped build error in page, got %q", body)
+	}
+}
+
+func TestProxy_BuildFailedKeptAlive(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "previous")
+	}))
+	defer backend.Close()
+
+	p, err := NewProxy(Options{Target: backend.URL}, nil)
+	if err != nil {
+		t.Fatalf("NewProxy returned error: %v", err)
+	}
+
+	p.Notify(command.Event{Type: command.EventStarted})
+	p.Notify(command.Event{Type: command.EventBuildStarted})
+	p.Notify(command.Event{
+		Type:      command.EventBuildFailed,
+		Err:       errors.New("exit status 1"),
+		KeptAlive: true,
+	})
+
+	recorder := httptest.NewRecorder()
+	p.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "previous" {
+		t.Errorf("Expected the previous version to be proxied, got %d %q", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestProxy_InjectScript(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/data" {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(w, `{"body": "</body>"}`)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = io.WriteString(w, "<html><body><h1>Hello</h1></BODY></html>")
+	}))
+	defer backend.Close()
+
+	p, err := NewProxy(Options{Target: backend.URL}, nil)
+	if err != nil {
+		t.Fatalf("NewProxy returned error: %v", err)
+	}
+
+	p.EnableLiveReload(http.NotFoundHandler())
+	p.Notify(command.Event{Type: command.EventStarted})
+
+	recorder := httptest.NewRecorder()
+	p.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	expected := `<html><body><h1>Hello</h1>` + string(scriptTag) + `</BODY></html>`
+	if recorder.Body.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, recorder.Body.String())
+	}
+
+	recorder = httptest.NewRecorder()
+	p.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/data", nil))
+
+	if strings.Contains(recorder.Body.String(), "<script") {
+		t.Errorf("Script must not be injected into non HTML responses, got %q", recorder.Body.String())
+	}
+}
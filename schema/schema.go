@@ -8,7 +8,9 @@ import (
 
 	"github.com/lasfh/eletrize/command"
 	"github.com/lasfh/eletrize/environments"
+	"github.com/lasfh/eletrize/livereload"
 	"github.com/lasfh/eletrize/output"
+	"github.com/lasfh/eletrize/proxy"
 	"github.com/lasfh/eletrize/watcher"
 )
 
@@ -18,16 +20,20 @@ const (
 )
 
 type Schema struct {
-	Envs     environments.Envs `json:"envs" yaml:"envs"`
-	Commands command.Commands  `json:"commands" yaml:"commands"`
-	Label    *output.Label     `json:"label" yaml:"label"`
-	Workdir  string            `json:"workdir" yaml:"workdir"`
-	EnvFile  string            `json:"env_file" yaml:"env_file"`
-	Watcher  watcher.Options   `json:"watcher" yaml:"watcher"`
+	Envs       environments.Envs   `json:"envs" yaml:"envs"`
+	Commands   command.Commands    `json:"commands" yaml:"commands"`
+	Label      *output.Label       `json:"label" yaml:"label"`
+	Workdir    string              `json:"workdir" yaml:"workdir"`
+	EnvFile    string              `json:"env_file" yaml:"env_file"`
+	Watcher    watcher.Options     `json:"watcher" yaml:"watcher"`
+	Proxy      *proxy.Options      `json:"proxy" yaml:"proxy"`
+	LiveReload *livereload.Options `json:"live_reload" yaml:"live_reload"`
+	liveReload *livereload.Server
 }
 
 // Start initializes the schema, setting the working directory, loading environment variables,
-// starting the file watcher, and launching the configured commands.
+// starting the file watcher, the development proxy and the live reload server, and launching
+// the configured commands.
 // It continues to watch for file events and triggers command execution on changes.
 //
 // Parameters:
@@ -59,11 +65,25 @@ func (s *Schema) Start(ctx context.Context) error {
 
 	defer w.Close()
 
+	s.Commands.SetFingerprint(w.Fingerprint)
+
 	if err := w.Start(); err != nil {
 		return err
 	}
 
-	if err := s.Commands.Start(s.Label, s.Envs); err != nil {
+	workdir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	closeServers, err := s.startServers()
+	if err != nil {
+		return err
+	}
+
+	defer closeServers()
+
+	if err := s.Commands.Start(s.Label, s.Envs, workdir); err != nil {
 		return err
 	}
 
@@ -77,6 +97,64 @@ func (s *Schema) Start(ctx context.Context) error {
 
 		output.Pushf(labelWatcher, "%s %s: %s\n", event.Op.String(), fileType, event.Name)
 
-		s.Commands.SendEvent()
+		if s.liveReload != nil && !isDir && s.liveReload.IsAsset(event.Name) {
+			s.liveReload.AssetChanged(event.Name)
+
+			return
+		}
+
+		s.Commands.SendEvent(event.Name)
 	})
 }
+
+// startServers starts the development proxy and the live reload server, when
+// configured, and subscribes them to the events of the commands. The returned
+// function closes them.
+func (s *Schema) startServers() (func(), error) {
+	var closers []func() error
+
+	closeServers := func() {
+		for _, closer := range closers {
+			_ = closer()
+		}
+	}
+
+	var p *proxy.Proxy
+
+	if s.Proxy != nil {
+		var err error
+
+		p, err = proxy.NewProxy(*s.Proxy, s.Label)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if s.LiveReload != nil {
+		s.liveReload = livereload.NewServer(*s.LiveReload, s.Label)
+
+		if p != nil {
+			p.EnableLiveReload(s.liveReload.Handler())
+		}
+
+		if err := s.liveReload.Start(); err != nil {
+			return nil, err
+		}
+
+		closers = append(closers, s.liveReload.Close)
+		s.Commands.Subscribe(s.liveReload.Notify)
+	}
+
+	if p != nil {
+		if err := p.Start(); err != nil {
+			closeServers()
+
+			return nil, err
+		}
+
+		closers = append(closers, p.Close)
+		s.Commands.Subscribe(p.Notify)
+	}
+
+	return closeServers, nil
+}
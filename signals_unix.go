@@ -0,0 +1,17 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// controlSignals maps the signals that control eletrize to the keys of the
+// same commands: SIGHUP rebuilds, SIGUSR1 restarts and SIGUSR2 pauses or
+// resumes watching.
+var controlSignals = map[os.Signal]byte{
+	syscall.SIGHUP:  'b',
+	syscall.SIGUSR1: 'r',
+	syscall.SIGUSR2: 'p',
+}
@@ -0,0 +1,6 @@
+package main
+
+import "os"
+
+// controlSignals is empty, since Windows has no signals to control eletrize.
+var controlSignals = map[os.Signal]byte{}
@@ -127,9 +127,13 @@ func (c configuration) Schema(workspaceDir string) (schema.Schema, bool) {
 			ExcludedPaths: []string{"vendor"},
 		},
 		Commands: command.Commands{
-			Build: &command.Command{
-				Method: "go",
-				Args:   []string{"build", "-o", customName, name},
+			Build: command.Pipeline{
+				{
+					Command: command.Command{
+						Method: "go",
+						Args:   []string{"build", "-o", customName, name},
+					},
+				},
 			},
 			Run: []command.Command{
 				{
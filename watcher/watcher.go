@@ -2,6 +2,9 @@ package watcher
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"io"
 	"io/fs"
 	"os"
@@ -113,6 +116,77 @@ func (w *Watcher) getDirectories(root string) (files []string, err error) {
 	return files, err
 }
 
+// Fingerprint returns a hash of the names and contents of the files
+// watched, the ones with a watched extension outside the excluded paths,
+// which changes whenever any of them changes. Files whose absolute path
+// matches one of the exclude patterns, such as build outputs, are left out.
+func (w *Watcher) Fingerprint(exclude ...string) (string, error) {
+	hash := sha256.New()
+
+	err := filepath.WalkDir(w.options.Path, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			if w.options.matchesExcludedPath(name) || (!w.options.Recursive && name != w.options.Path) {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if !entry.Type().IsRegular() || !w.options.matchesExtensions(name) || matchesAny(name, exclude) {
+			return nil
+		}
+
+		sum, err := fileHash(name)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(hash, "%s\x00%x\n", filepath.ToSlash(name), sum)
+
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func matchesAny(name string, patterns []string) bool {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return false
+	}
+
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, abs); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+func fileHash(name string) ([]byte, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return nil, err
+	}
+
+	return hash.Sum(nil), nil
+}
+
 func (w *Watcher) Close() error {
 	return w.notify.Close()
 }
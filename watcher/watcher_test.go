@@ -170,3 +170,78 @@ func TestWatcher_Integration(t *testing.T) {
 
 	mu.Unlock()
 }
+
+func TestWatcher_Fingerprint(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.Chdir(wd)
+
+	for _, dir := range []string{"pkg", "vendor"} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write := func(name, content string) {
+		t.Helper()
+
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("main.go", "package main")
+	write(filepath.Join("pkg", "pkg.go"), "package pkg")
+
+	w, err := NewWatcher(Options{
+		Extensions:    []string{".go"},
+		ExcludedPaths: []string{"vendor"},
+		Recursive:     true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+
+	defer w.Close()
+
+	fingerprint := func() string {
+		t.Helper()
+
+		sum, err := w.Fingerprint()
+		if err != nil {
+			t.Fatalf("Fingerprint: %v", err)
+		}
+
+		return sum
+	}
+
+	original := fingerprint()
+
+	// Files that are not watched do not change the fingerprint.
+	write("README.md", "readme")
+	write(filepath.Join("vendor", "dep.go"), "package dep")
+
+	if got := fingerprint(); got != original {
+		t.Errorf("Expected unwatched files not to change the fingerprint")
+	}
+
+	write(filepath.Join("pkg", "pkg.go"), "package pkg // edited")
+
+	if got := fingerprint(); got == original {
+		t.Errorf("Expected an edit to change the fingerprint")
+	}
+
+	// Undoing the edit restores the fingerprint.
+	write(filepath.Join("pkg", "pkg.go"), "package pkg")
+
+	if got := fingerprint(); got != original {
+		t.Errorf("Expected the original fingerprint after undoing the edit")
+	}
+}
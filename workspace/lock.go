@@ -0,0 +1,144 @@
+package workspace
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+var errLockHeld = errors.New("lock is held by another process")
+
+// Owner describes the eletrize holding the lock of a workspace.
+type Owner struct {
+	PID       int       `json:"pid"`
+	StartedAt time.Time `json:"started_at"`
+	Config    string    `json:"config,omitempty"`
+}
+
+func (o Owner) String() string {
+	description := fmt.Sprintf("PID %d, started at %s", o.PID, o.StartedAt.Format(time.DateTime))
+	if o.Config != "" {
+		description += " with " + o.Config
+	}
+
+	return description
+}
+
+// LockedError is returned when the workspace is locked by another eletrize.
+type LockedError struct {
+	Owner Owner
+}
+
+func (e *LockedError) Error() string {
+	if e.Owner.PID == 0 {
+		return "program is already running in this directory. Please close it before starting a new one"
+	}
+
+	return fmt.Sprintf(
+		"program is already running in this directory (%s). Please close it before starting a new one",
+		e.Owner,
+	)
+}
+
+// Lock is the lock of a workspace, held while eletrize runs in it.
+type Lock struct {
+	file *os.File
+	// Stale is the owner of a previous lock that was never released, because
+	// its eletrize crashed or was killed.
+	Stale *Owner
+}
+
+// Acquire locks the workspace in dir for this process, recording the
+// configuration file in use. It fails with a *LockedError when another
+// eletrize holds the lock. Where files cannot be locked, the lock is only
+// considered held while the process that wrote it is running.
+func Acquire(dir, config string) (*Lock, error) {
+	if err := PrepareRuntimeDir(); err != nil {
+		return nil, fmt.Errorf("lock: %w", err)
+	}
+
+	file, err := os.OpenFile(RuntimeFile(dir, ".lock"), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("lock: %w", err)
+	}
+
+	previous := readOwner(file)
+
+	if err := lockFile(file); err != nil {
+		if !errors.Is(err, errLockHeld) && !errors.Is(err, errors.ErrUnsupported) {
+			_ = file.Close()
+
+			return nil, fmt.Errorf("lock: %w", err)
+		}
+
+		var owner Owner
+		if previous != nil {
+			owner = *previous
+		}
+
+		if errors.Is(err, errLockHeld) || (owner.PID != 0 && processAlive(owner.PID)) {
+			_ = file.Close()
+
+			return nil, &LockedError{Owner: owner}
+		}
+	}
+
+	owner := Owner{
+		PID:       os.Getpid(),
+		StartedAt: time.Now(),
+		Config:    config,
+	}
+
+	if err := writeOwner(file, &owner); err != nil {
+		_ = file.Close()
+
+		return nil, fmt.Errorf("lock: %w", err)
+	}
+
+	return &Lock{file: file, Stale: previous}, nil
+}
+
+// Release releases the lock, leaving the lock file empty so the next
+// eletrize does not consider it stale.
+func (l *Lock) Release() error {
+	_ = writeOwner(l.file, nil)
+
+	return l.file.Close()
+}
+
+// readOwner returns the owner recorded in the lock file, if any.
+func readOwner(file *os.File) *Owner {
+	data, err := io.ReadAll(io.NewSectionReader(file, 0, 1<<16))
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+
+	var owner Owner
+	if err := json.Unmarshal(data, &owner); err != nil || owner.PID == 0 {
+		return nil
+	}
+
+	return &owner
+}
+
+func writeOwner(file *os.File, owner *Owner) error {
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+
+	if owner == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(owner)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.WriteAt(data, 0)
+
+	return err
+}
@@ -0,0 +1,81 @@
+package workspace
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAcquire(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+
+	dir := t.TempDir()
+
+	lock, err := Acquire(dir, "eletrize.yml")
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+
+	if lock.Stale != nil {
+		t.Errorf("Expected no stale lock, got %v", lock.Stale)
+	}
+
+	_, err = Acquire(dir, "other.yml")
+
+	var locked *LockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("Expected a *LockedError, got %v", err)
+	}
+
+	if locked.Owner.PID != os.Getpid() || locked.Owner.Config != "eletrize.yml" {
+		t.Errorf("Unexpected owner: %+v", locked)
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+
+	lock, err = Acquire(dir, "")
+	if err != nil {
+		t.Fatalf("Acquire after Release: %v", err)
+	}
+
+	defer lock.Release()
+
+	if lock.Stale != nil {
+		t.Errorf("Expected a released lock not to be stale, got %v", lock.Stale)
+	}
+}
+
+func TestAcquire_Stale(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+
+	dir := t.TempDir()
+
+	// A lock left by an eletrize that was killed: the file still names it,
+	// but nothing holds the lock.
+	owner := Owner{PID: os.Getpid(), StartedAt: time.Now(), Config: "eletrize.yml"}
+
+	file, err := os.Create(RuntimeFile(dir, ".lock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeOwner(file, &owner); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = file.Close()
+
+	lock, err := Acquire(dir, "")
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+
+	defer lock.Release()
+
+	if lock.Stale == nil || lock.Stale.PID != owner.PID || lock.Stale.Config != owner.Config {
+		t.Errorf("Expected the stale owner %+v, got %+v", owner, lock.Stale)
+	}
+}
@@ -0,0 +1,39 @@
+//go:build !windows
+
+package workspace
+
+import (
+	"errors"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+func lockFile(file *os.File) error {
+	err := unix.Flock(int(file.Fd()), unix.LOCK_EX|unix.LOCK_NB)
+
+	switch {
+	case errors.Is(err, unix.EWOULDBLOCK):
+		return errLockHeld
+	case errors.Is(err, unix.ENOLCK), errors.Is(err, unix.ENOTSUP), errors.Is(err, unix.EOPNOTSUPP):
+		return errors.ErrUnsupported
+	}
+
+	return err
+}
+
+func processAlive(pid int) bool {
+	err := unix.Kill(pid, 0)
+
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// interruptGroup sends SIGINT to the process group led by pid.
+func interruptGroup(pid int) {
+	_ = unix.Kill(-pid, unix.SIGINT)
+}
+
+func killGroup(pid int) {
+	_ = unix.Kill(-pid, unix.SIGKILL)
+}
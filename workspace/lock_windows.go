@@ -0,0 +1,41 @@
+package workspace
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+func lockFile(file *os.File) error {
+	// The locked byte is beyond the owner written to the file, which stays
+	// readable by other processes.
+	overlapped := windows.Overlapped{OffsetHigh: 1}
+
+	err := windows.LockFileEx(
+		windows.Handle(file.Fd()),
+		windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY,
+		0, 1, 0, &overlapped,
+	)
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return errLockHeld
+	}
+
+	return err
+}
+
+func processAlive(pid int) bool {
+	process, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+
+	defer windows.CloseHandle(process)
+
+	var code uint32
+	if err := windows.GetExitCodeProcess(process, &code); err != nil {
+		return false
+	}
+
+	return code == 259 // STILL_ACTIVE
+}
@@ -0,0 +1,233 @@
+package workspace
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lasfh/eletrize/output"
+)
+
+const (
+	leftoverGracePeriod = 2 * time.Second
+	leftoverPollDelay   = 50 * time.Millisecond
+)
+
+// Session records the processes started by an eletrize and the artifacts it
+// creates in a session file, removed when the session ends. A session file
+// found on start was left by an eletrize that was killed, and what it
+// recorded is cleaned up.
+type Session struct {
+	mu    sync.Mutex
+	path  string
+	state sessionState
+}
+
+type sessionState struct {
+	PID       int              `json:"pid"`
+	Processes []sessionProcess `json:"processes"`
+	Artifacts []string         `json:"artifacts"`
+}
+
+// sessionProcess is a process started by eletrize. The start time tells it
+// apart from a process that reused its PID.
+type sessionProcess struct {
+	PID   int    `json:"pid"`
+	Start uint64 `json:"start"`
+	Name  string `json:"name"`
+}
+
+func (p sessionProcess) String() string {
+	return fmt.Sprintf("%d (%s)", p.PID, p.Name)
+}
+
+// StartSession starts the session of the schema number running in dir,
+// after cleaning up its previous session if it did not end.
+func StartSession(dir string, schema int) (*Session, error) {
+	s := &Session{
+		path:  RuntimeFile(dir, fmt.Sprintf("-%d.session", schema)),
+		state: sessionState{PID: os.Getpid()},
+	}
+
+	if data, err := os.ReadFile(s.path); err == nil {
+		var previous sessionState
+		if err := json.Unmarshal(data, &previous); err == nil && !processAlive(previous.PID) {
+			cleanUp(previous)
+		}
+	}
+
+	if err := s.save(); err != nil {
+		return nil, fmt.Errorf("session: %w", err)
+	}
+
+	return s, nil
+}
+
+// End removes the session file, once the processes have stopped and the
+// artifacts have been removed.
+func (s *Session) End() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_ = os.Remove(s.path)
+}
+
+func (s *Session) AddProcess(pid int, name string) {
+	start, _ := processStart(pid)
+
+	s.update(func(state *sessionState) {
+		state.Processes = append(state.Processes, sessionProcess{PID: pid, Start: start, Name: name})
+	})
+}
+
+func (s *Session) RemoveProcess(pid int) {
+	s.update(func(state *sessionState) {
+		state.Processes = slices.DeleteFunc(state.Processes, func(p sessionProcess) bool {
+			return p.PID == pid
+		})
+	})
+}
+
+func (s *Session) AddArtifact(path string) {
+	s.update(func(state *sessionState) {
+		if !slices.Contains(state.Artifacts, path) {
+			state.Artifacts = append(state.Artifacts, path)
+		}
+	})
+}
+
+func (s *Session) RemoveArtifact(path string) {
+	s.update(func(state *sessionState) {
+		state.Artifacts = slices.DeleteFunc(state.Artifacts, func(artifact string) bool {
+			return artifact == path
+		})
+	})
+}
+
+func (s *Session) update(change func(state *sessionState)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	change(&s.state)
+
+	_ = s.save()
+}
+
+// save replaces the session file at once, so an eletrize killed while saving
+// never leaves a partial file that could not be cleaned up.
+func (s *Session) save() error {
+	data, err := json.Marshal(s.state)
+	if err != nil {
+		return err
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(s.path), "."+filepath.Base(s.path)+"-*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+
+		return err
+	}
+
+	if err := temp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), s.path)
+}
+
+// cleanUp kills the processes of a previous session that are still running
+// and removes its artifacts.
+func cleanUp(previous sessionState) {
+	var leftovers []sessionProcess
+
+	for _, p := range previous.Processes {
+		// Without its start time, a process cannot be told apart from
+		// another one that reused its PID.
+		if p.Start == 0 {
+			continue
+		}
+
+		if start, ok := processStart(p.PID); ok && start == p.Start {
+			leftovers = append(leftovers, p)
+		}
+	}
+
+	if len(leftovers) > 0 {
+		killLeftovers(leftovers)
+
+		output.Pushf(
+			output.LabelEletrize,
+			"KILLED %d LEFTOVER PROCESS(ES) OF A PREVIOUS SESSION: %s\n",
+			len(leftovers), joinProcesses(leftovers),
+		)
+	}
+
+	var removed []string
+
+	for _, artifact := range previous.Artifacts {
+		if err := os.Remove(artifact); err == nil {
+			removed = append(removed, artifact)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			output.Pushf(output.LabelEletrize, "FAILED TO REMOVE STALE ARTIFACT: %s\n", err)
+		}
+	}
+
+	if len(removed) > 0 {
+		output.Pushf(
+			output.LabelEletrize,
+			"REMOVED %d STALE ARTIFACT(S): %s\n",
+			len(removed), strings.Join(removed, ", "),
+		)
+	}
+}
+
+// killLeftovers interrupts the leftover processes and their process groups,
+// and kills the ones still running after a grace period.
+func killLeftovers(leftovers []sessionProcess) {
+	leftovers = slices.Clone(leftovers)
+
+	for _, p := range leftovers {
+		interruptGroup(p.PID)
+	}
+
+	deadline := time.Now().Add(leftoverGracePeriod)
+
+	for time.Now().Before(deadline) {
+		leftovers = slices.DeleteFunc(leftovers, func(p sessionProcess) bool {
+			start, ok := processStart(p.PID)
+
+			return !ok || start != p.Start
+		})
+
+		if len(leftovers) == 0 {
+			return
+		}
+
+		time.Sleep(leftoverPollDelay)
+	}
+
+	for _, p := range leftovers {
+		killGroup(p.PID)
+	}
+}
+
+func joinProcesses(processes []sessionProcess) string {
+	names := make([]string, len(processes))
+	for i, p := range processes {
+		names[i] = p.String()
+	}
+
+	return strings.Join(names, ", ")
+}
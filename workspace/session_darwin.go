@@ -0,0 +1,19 @@
+package workspace
+
+import "golang.org/x/sys/unix"
+
+// zombie is the SZOMB state of a process that exited and was not reaped.
+const zombie = 5
+
+// processStart returns the start time of a running process, in microseconds
+// since the epoch.
+func processStart(pid int) (uint64, bool) {
+	info, err := unix.SysctlKinfoProc("kern.proc.pid", pid)
+	if err != nil || info.Proc.P_pid != int32(pid) || info.Proc.P_stat == zombie {
+		return 0, false
+	}
+
+	start := info.Proc.P_starttime
+
+	return uint64(start.Sec)*1e6 + uint64(start.Usec), true
+}
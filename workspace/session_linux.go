@@ -0,0 +1,32 @@
+package workspace
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// processStart returns the start time of a running process, in clock ticks
+// since boot.
+func processStart(pid int) (uint64, bool) {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return 0, false
+	}
+
+	// The fields are read after the command name, which may contain spaces.
+	end := bytes.LastIndexByte(data, ')')
+	if end < 0 {
+		return 0, false
+	}
+
+	fields := strings.Fields(string(data[end+1:]))
+	if len(fields) < 20 || fields[0] == "Z" {
+		return 0, false
+	}
+
+	start, err := strconv.ParseUint(fields[19], 10, 64)
+
+	return start, err == nil
+}
@@ -0,0 +1,146 @@
+//go:build linux || darwin
+
+package workspace
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestStartSession(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+
+	dir := t.TempDir()
+
+	session, err := StartSession(dir, 1)
+	if err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+
+	artifact := filepath.Join(dir, "app")
+	session.AddProcess(os.Getpid(), "app")
+	session.AddArtifact(artifact)
+	session.AddArtifact(artifact)
+
+	state := readSession(t, dir)
+	if len(state.Processes) != 1 || state.Processes[0].Start == 0 || len(state.Artifacts) != 1 {
+		t.Errorf("Unexpected session: %+v", state)
+	}
+
+	session.RemoveProcess(os.Getpid())
+	session.RemoveArtifact(artifact)
+
+	state = readSession(t, dir)
+	if len(state.Processes) != 0 || len(state.Artifacts) != 0 {
+		t.Errorf("Expected an empty session, got %+v", state)
+	}
+
+	session.End()
+
+	if _, err := os.Stat(RuntimeFile(dir, "-1.session")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the session file to be removed, got %v", err)
+	}
+
+	// The session file is saved through temporary files, none left behind.
+	if entries, err := os.ReadDir(filepath.Dir(RuntimeFile(dir, ""))); err != nil || len(entries) != 0 {
+		t.Errorf("Expected an empty runtime directory, got %v (%v)", entries, err)
+	}
+}
+
+func TestStartSession_Leftovers(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+
+	dir := t.TempDir()
+
+	// The eletrize of the previous session, killed before cleaning up.
+	killed := exec.Command("true")
+	if err := killed.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	leftover := exec.Command("sleep", "60")
+	leftover.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	if err := leftover.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		_ = leftover.Wait()
+		close(exited)
+	}()
+
+	start, ok := processStart(leftover.Process.Pid)
+	if !ok {
+		t.Fatal("Expected the start time of the leftover process")
+	}
+
+	artifact := filepath.Join(dir, "app")
+	if err := os.WriteFile(artifact, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	previous := sessionState{
+		PID: killed.Process.Pid,
+		Processes: []sessionProcess{
+			{PID: leftover.Process.Pid, Start: start, Name: "app"},
+			// A process that reused a recorded PID is not killed.
+			{PID: os.Getpid(), Start: 1, Name: "reused"},
+		},
+		Artifacts: []string{artifact},
+	}
+
+	data, err := json.Marshal(previous)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(RuntimeFile(dir, "-1.session"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := StartSession(dir, 1)
+	if err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+
+	defer session.End()
+
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		_ = leftover.Process.Kill()
+		t.Fatal("Expected the leftover process to be killed")
+	}
+
+	if _, err := os.Stat(artifact); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the stale artifact to be removed, got %v", err)
+	}
+
+	if state := readSession(t, dir); state.PID != os.Getpid() || len(state.Processes) != 0 {
+		t.Errorf("Expected a new session, got %+v", state)
+	}
+}
+
+func readSession(t *testing.T, dir string) sessionState {
+	t.Helper()
+
+	data, err := os.ReadFile(RuntimeFile(dir, "-1.session"))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+
+	var state sessionState
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	return state
+}
@@ -0,0 +1,37 @@
+package workspace
+
+import "golang.org/x/sys/windows"
+
+// processStart returns the creation time of a running process.
+func processStart(pid int) (uint64, bool) {
+	process, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return 0, false
+	}
+
+	defer windows.CloseHandle(process)
+
+	var creation, exit, kernel, user windows.Filetime
+	if err := windows.GetProcessTimes(process, &creation, &exit, &kernel, &user); err != nil {
+		return 0, false
+	}
+
+	return uint64(creation.Nanoseconds()), processAlive(pid)
+}
+
+// interruptGroup terminates the process, since Windows has no process
+// groups to interrupt.
+func interruptGroup(pid int) {
+	killGroup(pid)
+}
+
+func killGroup(pid int) {
+	process, err := windows.OpenProcess(windows.PROCESS_TERMINATE, false, uint32(pid))
+	if err != nil {
+		return
+	}
+
+	defer windows.CloseHandle(process)
+
+	_ = windows.TerminateProcess(process, 1)
+}
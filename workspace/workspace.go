@@ -0,0 +1,63 @@
+// Package workspace locates the files of the eletrize running in a
+// workspace, the directory it is started in, and makes sure only one
+// eletrize runs in each workspace.
+package workspace
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// RuntimeFile returns the path of a file of the workspace in dir, named
+// after the absolute path of dir with the given suffix, in the runtime
+// directory. The name is kept short enough for a socket.
+func RuntimeFile(dir, suffix string) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+
+	sum := sha256.Sum256([]byte(dir))
+
+	return filepath.Join(runtimeDir(), "eletrize-"+hex.EncodeToString(sum[:8])+suffix)
+}
+
+// runtimeDir returns $XDG_RUNTIME_DIR or, without it, a directory of the
+// user in the temporary directory.
+func runtimeDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return dir
+	}
+
+	return userTempDir()
+}
+
+// PrepareRuntimeDir creates the runtime directory when it is the one in the
+// temporary directory, which is shared with the other users, and makes sure
+// no other user can have created or changed it, to squat the lock or the
+// socket of a workspace.
+func PrepareRuntimeDir() error {
+	if os.Getenv("XDG_RUNTIME_DIR") != "" {
+		return nil
+	}
+
+	dir := userTempDir()
+
+	if err := os.Mkdir(dir, 0o700); err != nil && !errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("runtime directory: %w", err)
+	}
+
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return fmt.Errorf("runtime directory: %w", err)
+	}
+
+	if err := checkPrivateDir(info); err != nil {
+		return fmt.Errorf("runtime directory %s: %w", dir, err)
+	}
+
+	return nil
+}
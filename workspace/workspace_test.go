@@ -0,0 +1,39 @@
+//go:build !windows
+
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPrepareRuntimeDir(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	t.Setenv("TMPDIR", t.TempDir())
+
+	if err := PrepareRuntimeDir(); err != nil {
+		t.Fatalf("PrepareRuntimeDir: %v", err)
+	}
+
+	dir := userTempDir()
+
+	info, err := os.Stat(dir)
+	if err != nil || info.Mode().Perm() != 0o700 {
+		t.Fatalf("Expected a private runtime directory, got %v (%v)", info, err)
+	}
+
+	if file := RuntimeFile(".", ".lock"); filepath.Dir(file) != dir {
+		t.Errorf("Expected %s in %s", file, dir)
+	}
+
+	// A directory that other users can write to may have been tampered with.
+	if err := os.Chmod(dir, 0o777); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := PrepareRuntimeDir(); err == nil || !strings.Contains(err.Error(), "other users") {
+		t.Errorf("Expected a shared runtime directory to be rejected, got %v", err)
+	}
+}
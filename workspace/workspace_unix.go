@@ -0,0 +1,35 @@
+//go:build !windows
+
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+func userTempDir() string {
+	return filepath.Join(os.TempDir(), "eletrize-"+strconv.Itoa(os.Getuid()))
+}
+
+// checkPrivateDir checks that the directory belongs to the user and is only
+// accessible by them.
+func checkPrivateDir(info fs.FileInfo) error {
+	if !info.IsDir() {
+		return errors.New("not a directory")
+	}
+
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("owned by uid %d", stat.Uid)
+	}
+
+	if info.Mode().Perm()&0o077 != 0 {
+		return fmt.Errorf("accessible by other users (%s)", info.Mode().Perm())
+	}
+
+	return nil
+}
@@ -0,0 +1,21 @@
+package workspace
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// userTempDir returns the temporary directory, which is already private to
+// the user on windows.
+func userTempDir() string {
+	return os.TempDir()
+}
+
+func checkPrivateDir(info fs.FileInfo) error {
+	if !info.IsDir() {
+		return errors.New("not a directory")
+	}
+
+	return nil
+}